@@ -0,0 +1,58 @@
+package shopifysemaphore
+
+import "errors"
+
+// ErrThrottled should be passed to ReleaseWithErr when a request was
+// throttled by Shopify, such as a THROTTLED GraphQL error. It is treated
+// as a signal to reduce concurrency when adaptive concurrency is enabled.
+var ErrThrottled = errors.New("shopifysemaphore: request was throttled")
+
+// aimd is an additive-increase, multiplicative-decrease controller for the
+// number of Goroutines which can run at a time. The limit grows by one for
+// every limit's worth of successful requests, and halves on every pause or
+// throttled request, converging on the best sustainable concurrency.
+type aimd struct {
+	min       int // Minimum limit.
+	max       int // Maximum limit.
+	limit     int // Current limit.
+	successes int // Successful requests since the last change of limit.
+}
+
+// newAIMD returns a pointer to aimd starting at the limit (start), kept
+// within the minimum (min) and maximum (max).
+func newAIMD(start int, min int, max int) *aimd {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	return &aimd{min: min, max: max, limit: clampInt(start, min, max)}
+}
+
+// increase will record a successful request, increasing the limit by one
+// once a full limit's worth of requests have succeeded.
+func (a *aimd) increase() {
+	a.successes += 1
+	if a.successes >= a.limit {
+		a.successes = 0
+		a.limit = clampInt(a.limit+1, a.min, a.max)
+	}
+}
+
+// decrease will halve the limit.
+func (a *aimd) decrease() {
+	a.successes = 0
+	a.limit = clampInt(a.limit/2, a.min, a.max)
+}
+
+// clampInt will keep v within lo and hi.
+func clampInt(v int, lo int, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
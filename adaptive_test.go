@@ -0,0 +1,53 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"testing"
+)
+
+// TestAIMD should increase the limit by one after a full limit of
+// successes and halve it on a decrease, within the bounds.
+func TestAIMD(t *testing.T) {
+	a := newAIMD(4, 2, 5)
+	for i := 0; i < 4; i += 1 {
+		a.increase()
+	}
+	if a.limit != 5 {
+		t.Errorf("aimd.limit = %d; want 5", a.limit)
+	}
+
+	// Should not grow beyond max.
+	for i := 0; i < 5; i += 1 {
+		a.increase()
+	}
+	if a.limit != 5 {
+		t.Errorf("aimd.limit = %d; want 5", a.limit)
+	}
+
+	a.decrease()
+	if a.limit != 2 {
+		t.Errorf("aimd.limit = %d; want 2", a.limit)
+	}
+
+	// Should not shrink beyond min.
+	a.decrease()
+	if a.limit != 2 {
+		t.Errorf("aimd.limit = %d; want 2", a.limit)
+	}
+}
+
+// TestAdaptiveThrottled should halve the capacity of a Semaphore
+// when a request is released as throttled.
+func TestAdaptiveThrottled(t *testing.T) {
+	ctx := context.Background()
+	sema := newSemaphore(4, WithAdaptive(1, 8))
+	for i := 0; i < 4; i += 1 {
+		if err := sema.Acquire(ctx); err != nil {
+			t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+		}
+	}
+	sema.ReleaseWithErr(ErrThrottled)
+	if c := sema.capacity(); c != 2 {
+		t.Errorf("capacity() = %d; want 2", c)
+	}
+}
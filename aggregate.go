@@ -0,0 +1,41 @@
+package shopifysemaphore
+
+import "time"
+
+// Aggregate represents a combined, read-only view across several Balances,
+// such as for a capacity dashboard of a fleet managing many shops.
+type Aggregate struct {
+	Balances    int   // Number of balances combined.
+	Remaining   int64 // Total current remaining points.
+	Limit       int64 // Total maximum points available.
+	AtThreshold int   // Number of balances at or below their threshold.
+
+	// Earliest estimated time until a balance reaches its threshold, and
+	// the index of that balance, -1 if no balance is being drained.
+	Exhaustion time.Duration
+	Earliest   int
+}
+
+// AggregateBalance returns a combined view across the balances (bs), totalling
+// the current remaining points and limits, and finding which balance is
+// estimated to reach its threshold first through TimeToThreshold. Nil
+// balances are skipped.
+func AggregateBalance(bs []*Balance) Aggregate {
+	agg := Aggregate{Earliest: -1}
+	for i, b := range bs {
+		if b == nil {
+			continue
+		}
+		agg.Balances += 1
+		agg.Remaining += int64(b.Current())
+		agg.Limit += int64(b.limit())
+		if b.AtThreshold() {
+			agg.AtThreshold += 1
+		}
+		if dur, ok := b.TimeToThreshold(); ok && (agg.Earliest == -1 || dur < agg.Exhaustion) {
+			agg.Exhaustion = dur
+			agg.Earliest = i
+		}
+	}
+	return agg
+}
@@ -0,0 +1,27 @@
+package shopifysemaphore
+
+import "testing"
+
+// TestAggregateBalance should combine several balances, finding the
+// earliest to reach its threshold.
+func TestAggregateBalance(t *testing.T) {
+	a := NewBalance(100, 1000, 0)
+	a.Update(500)
+	b := NewBalance(100, 2000, 0)
+	b.Update(100)
+	c := NewBalance(100, 1000, 0)
+
+	agg := AggregateBalance([]*Balance{a, nil, b, c})
+	if agg.Balances != 3 || agg.Remaining != 1600 || agg.Limit != 4000 || agg.AtThreshold != 1 {
+		t.Errorf("AggregateBalance() = %+v; want 3 balances, 1600 remaining, 4000 limit, 1 at threshold", agg)
+	}
+
+	// Already at the threshold is the earliest.
+	if agg.Earliest != 2 || agg.Exhaustion != 0 {
+		t.Errorf("AggregateBalance() = %+v; want earliest 2 in 0s", agg)
+	}
+
+	if agg := AggregateBalance(nil); agg.Earliest != -1 || agg.Balances != 0 {
+		t.Errorf("AggregateBalance(nil) = %+v; want no balances and earliest -1", agg)
+	}
+}
@@ -0,0 +1,103 @@
+package shopifysemaphore
+
+// Level represents how close the Semaphore is to having to hold work back,
+// signalled through Backpressure so upstream producers can shed or delay
+// load before a pause is ever required.
+type Level int
+
+const (
+	LevelNormal   Level = iota // Below every watermark.
+	LevelElevated              // At or above the elevated watermark.
+	LevelCritical              // At or above the critical watermark.
+)
+
+// String returns the name of the level.
+func (l Level) String() string {
+	switch l {
+	case LevelNormal:
+		return "normal"
+	case LevelElevated:
+		return "elevated"
+	case LevelCritical:
+		return "critical"
+	}
+	return "unknown"
+}
+
+// Watermarks represents the fractions, between 0 and 1, at which the
+// Backpressure level is raised. Both slot utilization (spots held out of
+// the capacity) and point consumption (points used out of the Limit) are
+// compared against them, with the higher of the two deciding the level.
+// A watermark of 0 or less is never reached.
+type Watermarks struct {
+	Elevated float64 // Fraction at which the level becomes LevelElevated.
+	Critical float64 // Fraction at which the level becomes LevelCritical.
+}
+
+// level returns the level for the fraction (f) used.
+func (wm Watermarks) level(f float64) Level {
+	switch {
+	case wm.Critical > 0 && f >= wm.Critical:
+		return LevelCritical
+	case wm.Elevated > 0 && f >= wm.Elevated:
+		return LevelElevated
+	}
+	return LevelNormal
+}
+
+// backpressure holds the state for signalling the backpressure level.
+type backpressure struct {
+	marks Watermarks // Watermarks for raising the level.
+	level Level      // Last signalled level.
+	ch    chan Level // Signalled levels, only ever holding the latest.
+}
+
+// Backpressure returns a channel which receives the new Level each time
+// slot utilization or point consumption crosses one of the Watermarks set
+// by WithBackpressure. Only the latest level is held, a slow receiver will
+// skip levels it has not yet received instead of blocking the Semaphore.
+// It returns nil, which never receives, if WithBackpressure was not used.
+func (sem *Semaphore) Backpressure() <-chan Level {
+	if sem.bp == nil {
+		return nil
+	}
+	return sem.bp.ch
+}
+
+// signal will evaluate the backpressure level, sending it to the channel
+// if it has changed. It must be called while holding mu.
+func (sem *Semaphore) signal() {
+	bp := sem.bp
+	if bp == nil {
+		return
+	}
+
+	var used float64
+	if c := sem.capacity(); c > 0 {
+		used = float64(sem.inflight) / float64(c)
+	}
+	if lim := sem.model.Max(); lim > 0 {
+		used = max(used, float64(lim-sem.model.Current())/float64(lim))
+	}
+	lvl := bp.marks.level(used)
+	if lvl == bp.level {
+		return
+	}
+	bp.level = lvl
+
+	// Replace any level not yet received with the latest.
+	select {
+	case <-bp.ch:
+	default:
+	}
+	bp.ch <- lvl
+}
+
+// WithBackpressure is a functional option for Semaphore which will enable
+// signalling the backpressure level through Backpressure, raising it as
+// slot utilization or point consumption crosses the watermarks (wm).
+func WithBackpressure(wm Watermarks) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.bp = &backpressure{marks: wm, ch: make(chan Level, 1)}
+	}
+}
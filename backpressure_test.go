@@ -0,0 +1,66 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestBackpressure should signal the level as slot utilization and
+// point consumption cross the watermarks.
+func TestBackpressure(t *testing.T) {
+	ctx := context.Background()
+	sema := newSemaphore(4, WithBackpressure(Watermarks{Elevated: 0.5, Critical: 0.75}))
+	bp := sema.Backpressure()
+
+	recv := func(exlvl Level) {
+		t.Helper()
+		select {
+		case lvl := <-bp:
+			if lvl != exlvl {
+				t.Errorf("level = %v; want %v", lvl, exlvl)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("level not signalled; want %v", exlvl)
+		}
+	}
+
+	// 1 of 4 spots is below every watermark, nothing is signalled.
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	select {
+	case lvl := <-bp:
+		t.Errorf("level = %v; want no signal", lvl)
+	default:
+	}
+
+	// 2 of 4 spots is at the elevated watermark.
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	recv(LevelElevated)
+
+	// 3 of 4 spots is at the critical watermark.
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	recv(LevelCritical)
+
+	// Releasing to 1 of 4 spots with plenty of points is back to normal.
+	sema.Release(1000)
+	sema.Release(1000)
+	recv(LevelNormal)
+
+	// Consuming 60% of points is elevated, even with few spots held.
+	sema.Release(400)
+	recv(LevelElevated)
+}
+
+// TestBackpressureDisabled should return a nil channel
+// without WithBackpressure.
+func TestBackpressureDisabled(t *testing.T) {
+	if bp := newSemaphore(1).Backpressure(); bp != nil {
+		t.Errorf("Backpressure() = %v; want nil", bp)
+	}
+}
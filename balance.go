@@ -1,6 +1,9 @@
 package shopifysemaphore
 
 import (
+	"fmt"
+	"math"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -13,41 +16,414 @@ var ErrPts int32 = -1
 
 // Balance represents the information of point values and keeps track of
 // items such as the remaining points, threshold, limit, and refill rate.
+// The threshold, limit, and refill rate can be changed while in use
+// through SetThreshold, SetLimit, and SetRefillRate.
 type Balance struct {
 	Remaining  atomic.Int32 // Point balance remaining.
 	Threshold  int32        // Minimum point balance where we would consider handling with a "pause".
 	Limit      int32        // Maximum points available.
-	RefillRate int32        // Number of points refilled per second.
+	RefillRate float64      // Number of points refilled per second, may be fractional.
+
+	ThresholdPct float64 // Fraction of Limit the Threshold follows as the Limit changes, 0 for an absolute Threshold.
+
+	Floor int32 // Points kept untouched for other processes, on top of the Threshold.
+
+	QueryMax int32 // Maximum cost of a single query, 0 for only the Limit.
+
+	Overdraft      Overdraft // Behavior when consuming more points than remain.
+	OverdraftLimit int32     // Points Remaining may go below zero by, for OverdraftAllow.
+
+	AnomalyFunc func(int32) // Optional callback for when an out of range value is passed to Update.
+
+	StaleAfter time.Duration       // Duration without an update before Remaining is stale, 0 for never.
+	StaleFunc  func(time.Duration) // Optional callback for when Remaining is first noticed to be stale.
+
+	LimitsFunc func(int32, float64) // Optional callback for when Sync changes the Limit or RefillRate.
+
+	initial *int32 // Remaining points to start with, set by WithInitialRemaining, nil for the limit.
+
+	updatedAt     atomic.Int64 // When Remaining was last updated, in Unix nanoseconds.
+	staleNotified atomic.Bool  // If the StaleFunc was run since the last update.
+
+	mu sync.RWMutex // For handling Threshold, Limit, and RefillRate changes while in use.
+
+	vmu sync.Mutex // For handling Remaining changes together with updatedAt.
+
+	rmu      sync.Mutex  // For handling consumption tracking.
+	consumed consumption // Rolling average of points consumed.
+	calib    *calibrator // Refill rate calibration, nil if disabled.
+	costs    histogram   // Distribution of points consumed between updates.
+
+	wmu      sync.Mutex              // For handling watchers.
+	watchers map[chan int32]struct{} // Channels of Watch, receiving changes to Remaining.
+	history  []UpdateRecord          // Recent changes to Remaining, as a ring.
+	histN    int                     // Total number of changes recorded into the history.
 }
 
 // NewBalance accepts a threshold (thld) point balance, a maximum (max) point
-// balance, and the refill rate (rr). It will return a pointer to Balance.
-func NewBalance(thld int32, max int32, rr int32) *Balance {
+// balance, the refill rate (rr), and lastly, optional parameters. It will
+// return a pointer to Balance.
+func NewBalance(thld int32, max int32, rr int32, opts ...func(*Balance)) *Balance {
+	return NewBalanceFloat(thld, max, float64(rr), opts...)
+}
+
+// NewBalanceFloat returns a pointer to Balance in the same way as NewBalance,
+// but accepts a fractional refill rate (rr), such as 0.5 points per second.
+func NewBalanceFloat(thld int32, max int32, rr float64, opts ...func(*Balance)) *Balance {
 	b := &Balance{
 		Threshold:  thld,
 		Limit:      max,
 		RefillRate: rr,
 	}
-	b.Update(max)
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.AnomalyFunc == nil {
+		// Provide default AnomalyFunc.
+		WithAnomalyFunc(func(_ int32) {})(b)
+	}
+	if b.LimitsFunc == nil {
+		// Provide default LimitsFunc.
+		WithLimitsFunc(func(_ int32, _ float64) {})(b)
+	}
+	pts := max
+	if b.initial != nil {
+		pts = *b.initial
+	}
+	b.Update(pts)
+	return b
+}
+
+// NewBalanceE returns a pointer to Balance in the same way as NewBalanceFloat,
+// but will first validate the point information, returning an error if it
+// would not work. Such as a zero refill rate, which would otherwise cause a
+// divide-by-zero deep within Release, or a threshold which is never above.
+func NewBalanceE(thld int32, max int32, rr float64, opts ...func(*Balance)) (*Balance, error) {
+	if max <= 0 {
+		return nil, fmt.Errorf("%w: got %d", ErrInvalidLimit, max)
+	}
+	if rr <= 0 {
+		return nil, fmt.Errorf("%w: got %v", ErrInvalidRefillRate, rr)
+	}
+	if thld >= max {
+		return nil, fmt.Errorf("%w: got %d for a limit of %d", ErrInvalidThreshold, thld, max)
+	}
+	return NewBalanceFloat(thld, max, rr, opts...), nil
+}
+
+// NewBalancePct returns a pointer to Balance in the same way as NewBalanceFloat,
+// but the threshold is expressed as a fraction (pct) of the maximum (max),
+// such as 0.1 for 10%. The threshold is recomputed whenever the limit is
+// changed through SetLimit or Sync, so it stays correct as the plan or
+// bucket size changes.
+func NewBalancePct(pct float64, max int32, rr float64, opts ...func(*Balance)) *Balance {
+	b := NewBalanceFloat(pctOf(pct, max), max, rr, opts...)
+	b.ThresholdPct = pct
 	return b
 }
 
-// Update accepts a new value of remaining points to store.
+// pctOf returns the fraction (pct) of the maximum (max) in points.
+func pctOf(pct float64, max int32) int32 {
+	return int32(math.Round(pct * float64(max)))
+}
+
+// Update accepts a new value of remaining points to store. A value above the
+// limit, such as from a misparsed response or a plan change, is clamped to
+// the limit. A negative value, other than ErrPts, is ignored. Both are
+// reported to the AnomalyFunc, if set.
 func (b *Balance) Update(points int32) {
-	if points > ErrPts {
-		b.Remaining.Store(points)
+	b.update(points, SourceUpdate)
+}
+
+// update handles storing a new value of remaining points for Update and
+// Sync, recording the source (src) of the change.
+func (b *Balance) update(points int32, src UpdateSource) {
+	if points == ErrPts {
+		return
+	}
+	lim := b.limit()
+	if points < 0 || points > lim {
+		if b.AnomalyFunc != nil {
+			b.AnomalyFunc(points)
+		}
+		if points < 0 {
+			return
+		}
+		points = lim
+	}
+	now := time.Now()
+	b.vmu.Lock()
+	old := b.Remaining.Swap(points)
+	b.updatedAt.Store(now.UnixNano())
+	b.vmu.Unlock()
+	b.staleNotified.Store(false)
+
+	b.rmu.Lock()
+	rr := b.refillRate()
+	if used, ok := b.consumed.observe(points, lim, rr, now); ok {
+		b.costs.record(int32(math.Round(used)))
+	}
+	if b.calib != nil {
+		if est := b.calib.observe(points, lim, rr, now); est > 0 {
+			b.SetRefillRate(est)
+		}
+	}
+	b.rmu.Unlock()
+	b.changed(old, points, src)
+}
+
+// Consume will atomically subtract an estimated cost from the remaining
+// points, returning the new remaining points. This allows a request to be
+// accounted for optimistically, before its response with the actual remaining
+// points arrives. By default the remaining points are clamped at zero, see
+// WithOverdraft for other behaviors.
+func (b *Balance) Consume(cost int32) int32 {
+	pts, _ := b.consume(cost, false, SourceConsume)
+	return pts
+}
+
+// TryConsume will subtract the cost from the remaining points in the same way
+// as Consume, but returns false, consuming nothing, if the Overdraft is
+// OverdraftBlock and the current remaining points can not cover the cost.
+func (b *Balance) TryConsume(cost int32) (int32, bool) {
+	return b.consume(cost, false, SourceConsume)
+}
+
+// consume handles subtracting the cost from the remaining points for
+// Consume, TryConsume, and Reserve, following the Overdraft. If block
+// is true, the cost must be covered, within any overdraft allowed, for
+// any Overdraft. A cost which must be covered may not use the Floor.
+// The cost is subtracted from the current remaining points, which become the
+// stored value as of now, so the points refilled since the last update are
+// kept rather than lost. The source (src) of the change is recorded.
+func (b *Balance) consume(cost int32, block bool, src UpdateSource) (int32, bool) {
+	var floor int32
+	if b.Overdraft == OverdraftAllow {
+		floor = -b.OverdraftLimit
+	}
+	stale := b.stale()
+	b.vmu.Lock()
+	pts := b.Remaining.Load()
+	cur := b.extrapolate(pts)
+	if stale {
+		cur = b.limit()
+	}
+	if (block || b.Overdraft == OverdraftBlock) && cur-cost < floor+b.Floor {
+		b.vmu.Unlock()
+		return pts, false
+	}
+
+	next := cur - cost
+	if b.Overdraft != OverdraftBlock {
+		next = max(next, floor)
+	}
+	b.Remaining.Store(next)
+	if b.updatedAt.Load() != 0 {
+		// Rebase on the refilled estimate, now accounted for in next.
+		b.updatedAt.Store(time.Now().UnixNano())
+		b.staleNotified.Store(false)
+	}
+	b.vmu.Unlock()
+	if b.calib != nil {
+		b.rmu.Lock()
+		b.calib.spend(cur - next)
+		b.rmu.Unlock()
 	}
+	b.changed(pts, next, src)
+	return next, true
 }
 
-// RefillDuration accounts for the remaining points, the limit, and the refill rate to
+// Refund will atomically add points back to the remaining points, clamped
+// at the limit, returning the new remaining points. This is for when a
+// Consume turns out to be too high, such as the actual cost of a query being
+// lower than the requested cost, or the request failing before it was run.
+func (b *Balance) Refund(pts int32) int32 {
+	b.vmu.Lock()
+	cur := b.Remaining.Load()
+	next := min(cur+pts, b.limit())
+	b.Remaining.Store(next)
+	b.vmu.Unlock()
+	if b.calib != nil {
+		b.rmu.Lock()
+		b.calib.spend(cur - next)
+		b.rmu.Unlock()
+	}
+	b.changed(cur, next, SourceRefund)
+	return next
+}
+
+// Current returns the remaining points extrapolated from the last updated
+// value, adding the points refilled since at the refill rate, capped at the
+// limit. Between updates the stored value goes stale, as points continue to
+// refill, so this is a closer estimate of the actual balance. Once stale,
+// after StaleAfter, the balance is assumed to have refilled to the limit.
+func (b *Balance) Current() int32 {
+	if b.stale() {
+		return b.limit()
+	}
+	return b.extrapolate(b.Remaining.Load())
+}
+
+// extrapolate returns the remaining points (pts) with the points refilled
+// since the last update added, capped at the limit.
+func (b *Balance) extrapolate(pts int32) int32 {
+	at := b.updatedAt.Load()
+	rr := b.refillRate()
+	if at == 0 || rr <= 0 {
+		// Never updated, or nothing refills.
+		return pts
+	}
+
+	refilled := time.Since(time.Unix(0, at)).Seconds() * rr
+	return int32(min(float64(pts)+refilled, float64(b.limit())))
+}
+
+// RefillDuration accounts for the current remaining points, the limit, and the refill rate to
 // determine how many seconds it would take to refill to remaining points back to full.
-// It will return a duration which can be used to "pause" operations.
+// It will return a duration, rounded up to the nanosecond, which can be used to "pause" operations.
 func (b *Balance) RefillDuration() time.Duration {
-	return time.Duration((b.Limit-b.Remaining.Load())/b.RefillRate) * time.Second
+	secs := float64(b.limit()-b.Current()) / b.refillRate()
+	return time.Duration(math.Ceil(secs * float64(time.Second)))
 }
 
 // AtThreshold will return a boolean if we have reached or surpassed the set
-// threshold of remaining points or not.
+// threshold of current remaining points or not. Any Floor is kept on top of
+// the threshold, so it is reached that much earlier.
 func (b *Balance) AtThreshold() bool {
-	return b.Remaining.Load() <= b.Threshold
+	return b.Current() <= b.threshold()+b.Floor
+}
+
+// SetThreshold will safely replace the Threshold while the Balance is in use,
+// switching to an absolute Threshold if it was a fraction of the Limit.
+// Writing the Threshold field directly once the Balance is in use is racy.
+func (b *Balance) SetThreshold(thld int32) {
+	defer b.mu.Unlock()
+	b.mu.Lock()
+	b.Threshold = thld
+	b.ThresholdPct = 0
+}
+
+// SetLimit will safely replace the Limit while the Balance is in use, such
+// as when a different maximum is reported after a plan upgrade. Writing the
+// Limit field directly once the Balance is in use is racy.
+func (b *Balance) SetLimit(max int32) {
+	defer b.mu.Unlock()
+	b.mu.Lock()
+	b.setLimit(max)
+}
+
+// SetRefillRate will safely replace the RefillRate while the Balance is in
+// use. Writing the RefillRate field directly once the Balance is in use is racy.
+func (b *Balance) SetRefillRate(rr float64) {
+	defer b.mu.Unlock()
+	b.mu.Lock()
+	b.RefillRate = rr
+}
+
+// Sync will update the remaining points, limit, and refill rate together from
+// a single observation, such as the throttleStatus of a GraphQL response, so
+// a long running process follows plan or API version changes. A max of 0 or
+// less, or a restore rate (rr) of 0 or less, is left unchanged. If the limit
+// or refill rate changed, the LimitsFunc is run with the new values.
+func (b *Balance) Sync(remaining int32, max int32, rr float64) {
+	b.mu.Lock()
+	old, oldrr := b.Limit, b.RefillRate
+	if max > 0 {
+		b.setLimit(max)
+	}
+	if rr > 0 {
+		b.RefillRate = rr
+	}
+	lim, nrr, fn := b.Limit, b.RefillRate, b.LimitsFunc
+	b.mu.Unlock()
+	b.update(remaining, SourceSync)
+	if (lim != old || nrr != oldrr) && fn != nil {
+		fn(lim, nrr)
+	}
+}
+
+// setLimit will replace the Limit, recomputing the Threshold if it is a
+// fraction of the Limit. It must be called while holding mu.
+func (b *Balance) setLimit(max int32) {
+	b.Limit = max
+	if b.ThresholdPct > 0 {
+		b.Threshold = pctOf(b.ThresholdPct, max)
+	}
+}
+
+// threshold returns the Threshold, safe for use while it may be changing.
+func (b *Balance) threshold() int32 {
+	defer b.mu.RUnlock()
+	b.mu.RLock()
+	return b.Threshold
+}
+
+// limit returns the Limit, safe for use while it may be changing.
+func (b *Balance) limit() int32 {
+	defer b.mu.RUnlock()
+	b.mu.RLock()
+	return b.Limit
+}
+
+// refillRate returns the RefillRate, safe for use while it may be changing.
+func (b *Balance) refillRate() float64 {
+	defer b.mu.RUnlock()
+	b.mu.RLock()
+	return b.RefillRate
+}
+
+// WithAnomalyFunc is a functional option for Balance to call when a value
+// outside of 0 and the limit is passed to Update. The out of range value
+// will be passed into the function.
+func WithAnomalyFunc(fn func(int32)) func(*Balance) {
+	return func(b *Balance) {
+		b.AnomalyFunc = fn
+	}
+}
+
+// WithLimitsFunc is a functional option for Balance to call when Sync changes
+// the Limit or RefillRate, such as after the shop's plan is upgraded. The new
+// Limit and RefillRate will be passed into the function.
+func WithLimitsFunc(fn func(int32, float64)) func(*Balance) {
+	return func(b *Balance) {
+		b.LimitsFunc = fn
+	}
+}
+
+// WithInitialRemaining is a functional option for Balance which will start
+// the remaining points at pts instead of the limit, such as from a persisted
+// snapshot or a probe request, for a worker booting with a partially used
+// bucket.
+func WithInitialRemaining(pts int32) func(*Balance) {
+	return func(b *Balance) {
+		b.initial = &pts
+	}
+}
+
+// WithReserve is a functional option for Balance which will keep a floor of
+// points (pts) untouched, such as for interactive merchant facing requests
+// handled by another process, so they always find budget available. A pause
+// happens once the remaining points reach the threshold plus the floor, and
+// a Reserve or TryConsume which must be covered can not use the floor.
+func WithReserve(pts int32) func(*Balance) {
+	return func(b *Balance) {
+		b.Floor = pts
+	}
+}
+
+// WithQueryMax is a functional option for Balance which will set the maximum
+// cost of a single query (pts), such as GraphQLQueryMax, past which Reserve
+// and AcquireCost reject the query.
+func WithQueryMax(pts int32) func(*Balance) {
+	return func(b *Balance) {
+		b.QueryMax = pts
+	}
+}
+
+// WithHistory is a functional option for Balance which will keep the last
+// n changes to the remaining points, for History.
+func WithHistory(n int) func(*Balance) {
+	return func(b *Balance) {
+		b.history = make([]UpdateRecord, 0, n)
+	}
 }
@@ -0,0 +1,96 @@
+package shopifysemaphore
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Balance64 is a BalanceModel representing the information of point values
+// in the same way as Balance, but with 64-bit point accounting. This gives
+// headroom for large buckets, cost multipliers, and aggregated views of
+// several buckets which would overflow an int32. The remaining points are
+// estimated from the refill since the last update, and clamped to the limit.
+// Current and Max saturate at math.MaxInt32, but AtThreshold and
+// RefillDuration account for the full 64-bit points. It is safe for
+// concurrent use.
+type Balance64 struct {
+	Threshold  int64   // Minimum point balance where we would consider handling with a "pause".
+	Limit      int64   // Maximum points available.
+	RefillRate float64 // Number of points refilled per second, may be fractional.
+
+	mu  sync.Mutex // For handling the remaining points together with at.
+	pts int64      // Point balance remaining, as of at.
+	at  time.Time  // When the remaining points were last updated.
+}
+
+// NewBalance64 accepts a threshold (thld) point balance, a maximum (max) point
+// balance, and the refill rate (rr). It will return a pointer to Balance64,
+// starting full.
+func NewBalance64(thld int64, max int64, rr float64) *Balance64 {
+	b := &Balance64{
+		Threshold:  thld,
+		Limit:      max,
+		RefillRate: rr,
+	}
+	b.Update64(max)
+	return b
+}
+
+// Update will store a new value of remaining points, ignoring ErrPts, for
+// BalanceModel.
+func (b *Balance64) Update(points int32) {
+	b.Update64(int64(points))
+}
+
+// Update64 will store a new value of remaining points. Values below 0, such
+// as ErrPts, are ignored, and values above the limit are clamped to it.
+func (b *Balance64) Update64(points int64) {
+	if points < 0 {
+		return
+	}
+	defer b.mu.Unlock()
+	b.mu.Lock()
+	b.pts = min(points, b.Limit)
+	b.at = time.Now()
+}
+
+// Current64 returns the remaining points, as estimated from the refill since
+// the last update.
+func (b *Balance64) Current64() int64 {
+	defer b.mu.Unlock()
+	b.mu.Lock()
+	if b.RefillRate <= 0 {
+		return b.pts
+	}
+	refilled := time.Since(b.at).Seconds() * b.RefillRate
+	return int64(min(float64(b.pts)+refilled, float64(b.Limit)))
+}
+
+// Current returns the remaining points in the same way as Current64, but
+// saturated at math.MaxInt32, for BalanceModel.
+func (b *Balance64) Current() int32 {
+	return int32(min(b.Current64(), math.MaxInt32))
+}
+
+// Max returns the limit, saturated at math.MaxInt32, for BalanceModel.
+func (b *Balance64) Max() int32 {
+	return int32(min(b.Limit, math.MaxInt32))
+}
+
+// RefillDuration accounts for the remaining points, the limit, and the refill rate to
+// determine how many seconds it would take to refill to remaining points back to full.
+// It will return a duration, rounded up to the nanosecond, which can be used to "pause" operations.
+func (b *Balance64) RefillDuration() time.Duration {
+	if b.RefillRate <= 0 {
+		return 0
+	}
+	secs := float64(b.Limit-b.Current64()) / b.RefillRate
+	return time.Duration(math.Ceil(secs * float64(time.Second)))
+}
+
+// AtThreshold will return a boolean if we have reached or surpassed the set
+// threshold of remaining points or not.
+func (b *Balance64) AtThreshold() bool {
+	return b.Current64() <= b.Threshold
+}
@@ -0,0 +1,64 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+// TestBalance64 should account for points beyond the range of an int32.
+func TestBalance64(t *testing.T) {
+	var max int64 = math.MaxInt32 * 4
+	b := NewBalance64(max/10, max, float64(max)/100)
+	if r := b.Current64(); r != max {
+		t.Errorf("Balance64.Current64() = %d; want %d", r, max)
+	}
+	if r := b.Current(); r != math.MaxInt32 {
+		t.Errorf("Balance64.Current() = %d; want %d", r, math.MaxInt32)
+	}
+	if m := b.Max(); m != math.MaxInt32 {
+		t.Errorf("Balance64.Max() = %d; want %d", m, math.MaxInt32)
+	}
+
+	b.Update(ErrPts)
+	b.Update64(max * 2)
+	if r := b.Current64(); r != max {
+		t.Errorf("Balance64.Current64() = %d; want %d", r, max)
+	}
+
+	b.Update64(0)
+	if !b.AtThreshold() {
+		t.Errorf("Balance64.AtThreshold() = false; want true")
+	}
+	if dur := b.RefillDuration(); dur <= 99*time.Second || dur > 100*time.Second {
+		// Should be about 100s as max/(max/100) = 100.
+		t.Errorf("Balance64.RefillDuration() = %v; want about %v", dur, 100*time.Second)
+	}
+
+	// Fifty seconds later, half has refilled.
+	b.at = b.at.Add(-50 * time.Second)
+	if r := b.Current64(); r < max/2 || r > max/2+max/100 {
+		t.Errorf("Balance64.Current64() = %d; want about %d", r, max/2)
+	}
+	if b.AtThreshold() {
+		t.Errorf("Balance64.AtThreshold() = true; want false")
+	}
+}
+
+// TestBalance64Semaphore should pause a Semaphore until the Balance64 has
+// refilled.
+func TestBalance64Semaphore(t *testing.T) {
+	paused := make(chan time.Duration, 1)
+	ctx := context.Background()
+	sema := NewSemaphoreModel(1, NewBalance64(100, 1000, 100), WithPauseFunc(func(_ int32, dur time.Duration) {
+		paused <- dur
+	}))
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(50)
+	if dur := <-paused; dur <= 9*time.Second || dur > 10*time.Second {
+		t.Errorf("PauseFunc(_, %v); want about 9.5s", dur)
+	}
+}
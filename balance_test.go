@@ -1,6 +1,8 @@
 package shopifysemaphore
 
 import (
+	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 )
@@ -30,6 +32,36 @@ func TestRefillDuration(t *testing.T) {
 	}
 }
 
+// TestRefillDurationFractional should ensure we calculate the correct
+// duration with a fractional refill rate.
+func TestRefillDurationFractional(t *testing.T) {
+	b := NewBalanceFloat(10, 100, 0.5)
+	b.Update(90)
+	dur := b.RefillDuration()
+	exdur := 20 * time.Second
+	if dur != exdur {
+		// Should be 20s as (100-90)/0.5 = 20.
+		t.Errorf("Balance.RefillDuration() = %v; want %v", dur, exdur)
+	}
+}
+
+// TestRefillDurationSubSecond should ensure small deficits are not
+// truncated to whole seconds.
+func TestRefillDurationSubSecond(t *testing.T) {
+	b := newBalance()
+	b.Update(850)
+	if dur := b.RefillDuration(); dur != 1500*time.Millisecond {
+		// Should be 1.5s as (1000-850)/100 = 1.5.
+		t.Errorf("Balance.RefillDuration() = %v; want %v", dur, 1500*time.Millisecond)
+	}
+
+	b.Update(999)
+	if dur := b.RefillDuration(); dur != 10*time.Millisecond {
+		// Should be 10ms as (1000-999)/100 = 0.01.
+		t.Errorf("Balance.RefillDuration() = %v; want %v", dur, 10*time.Millisecond)
+	}
+}
+
 // TestAtThreshold should properly know when we are at the desired threshold.
 func TestAtThreshold(t *testing.T) {
 	b := newBalance()
@@ -61,8 +93,8 @@ func TestNewBalance(t *testing.T) {
 	if b.Threshold != thld {
 		t.Errorf("Balance.Threshold = %d; want %d", b.Threshold, thld)
 	}
-	if b.RefillRate != rr {
-		t.Errorf("Balance.RefillRate = %d; want %d", b.RefillRate, rr)
+	if b.RefillRate != float64(rr) {
+		t.Errorf("Balance.RefillRate = %v; want %d", b.RefillRate, rr)
 	}
 	if b.Remaining.Load() != limit {
 		t.Errorf("Balance.Remaining = %d; want %d", b.Remaining.Load(), limit)
@@ -86,3 +118,260 @@ func TestUpdate(t *testing.T) {
 		t.Errorf("Balance.Remaining = %d; want %d", rpts, expts)
 	}
 }
+
+// TestCurrent should extrapolate the remaining points from the last
+// update at the refill rate, capped at the limit.
+func TestCurrent(t *testing.T) {
+	b := NewBalance(100, 1000, 10000)
+	b.Update(0)
+	time.Sleep(20 * time.Millisecond)
+	if pts := b.Current(); pts < 200 || pts >= 1000 {
+		// Should be at least 200 as 10000*0.02 = 200.
+		t.Errorf("Balance.Current() = %d; want between 200 and 1000", pts)
+	}
+	if pts := b.Remaining.Load(); pts != 0 {
+		t.Errorf("Balance.Remaining = %d; want 0", pts)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if pts := b.Current(); pts != 1000 {
+		// Should be capped at the limit.
+		t.Errorf("Balance.Current() = %d; want 1000", pts)
+	}
+}
+
+// TestConsume should subtract the cost from the remaining points,
+// never going below zero.
+func TestConsume(t *testing.T) {
+	b := newBalance()
+	if pts := b.Consume(300); pts != 700 {
+		t.Errorf("Balance.Consume(300) = %d; want 700", pts)
+	}
+	if pts := b.Consume(800); pts != 0 {
+		// Should be clamped at zero.
+		t.Errorf("Balance.Consume(800) = %d; want 0", pts)
+	}
+	if pts := b.Remaining.Load(); pts != 0 {
+		t.Errorf("Balance.Remaining = %d; want 0", pts)
+	}
+}
+
+// TestConsumeRefilled should subtract the cost from the remaining points
+// refilled since the last update, keeping them.
+func TestConsumeRefilled(t *testing.T) {
+	b := NewBalance(0, 1000, 100)
+	b.Update(0)
+	b.updatedAt.Store(time.Now().Add(-500 * time.Millisecond).UnixNano())
+	if pts := b.Consume(40); pts < 10 || pts > 11 {
+		t.Errorf("Balance.Consume(40) = %d; want 10", pts)
+	}
+	if pts := b.Current(); pts < 10 || pts > 11 {
+		t.Errorf("Balance.Current() = %d; want 10", pts)
+	}
+
+	// Reservations hold what they took, and give back only that.
+	b.Update(0)
+	b.updatedAt.Store(time.Now().Add(-time.Second).UnixNano())
+	r, ok := b.Reserve(60)
+	if !ok {
+		t.Fatal("Balance.Reserve(60) = _, false; want true")
+	}
+	if pts := b.Current(); pts < 40 || pts > 41 {
+		t.Errorf("Balance.Current() = %d; want 40", pts)
+	}
+	r.Cancel()
+	if pts := b.Current(); pts < 100 || pts > 101 {
+		t.Errorf("Balance.Current() = %d; want 100", pts)
+	}
+}
+
+// TestRefund should add points back to the remaining points,
+// never going above the limit.
+func TestRefund(t *testing.T) {
+	b := newBalance()
+	b.Consume(500)
+	if pts := b.Refund(200); pts != 700 {
+		t.Errorf("Balance.Refund(200) = %d; want 700", pts)
+	}
+	if pts := b.Refund(800); pts != 1000 {
+		// Should be clamped at the limit.
+		t.Errorf("Balance.Refund(800) = %d; want 1000", pts)
+	}
+}
+
+// TestUpdateClamp should clamp values above the limit and ignore negative
+// values, reporting both as anomalies.
+func TestUpdateClamp(t *testing.T) {
+	var anomalies []int32
+	b := NewBalance(100, 1000, 100, WithAnomalyFunc(func(pts int32) {
+		anomalies = append(anomalies, pts)
+	}))
+
+	b.Update(5000)
+	if pts := b.Remaining.Load(); pts != 1000 {
+		t.Errorf("Balance.Remaining = %d; want 1000", pts)
+	}
+	if dur := b.RefillDuration(); dur != 0 {
+		t.Errorf("Balance.RefillDuration() = %v; want 0s", dur)
+	}
+
+	b.Update(500)
+	b.Update(-5)
+	if pts := b.Remaining.Load(); pts != 500 {
+		t.Errorf("Balance.Remaining = %d; want 500", pts)
+	}
+
+	// ErrPts is not an anomaly.
+	b.Update(ErrPts)
+	if len(anomalies) != 2 || anomalies[0] != 5000 || anomalies[1] != -5 {
+		t.Errorf("anomalies = %v; want [5000 -5]", anomalies)
+	}
+}
+
+// TestBalanceSetters should safely change the point information
+// while the Balance is in use.
+func TestBalanceSetters(t *testing.T) {
+	b := newBalance()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i += 1 {
+			b.AtThreshold()
+			b.RefillDuration()
+		}
+	}()
+	b.SetThreshold(200)
+	b.SetLimit(2000)
+	b.SetRefillRate(50)
+	<-done
+
+	if b.Threshold != 200 || b.Limit != 2000 || b.RefillRate != 50 {
+		t.Errorf("Balance = %d, %d, %v; want 200, 2000, 50", b.Threshold, b.Limit, b.RefillRate)
+	}
+	if dur := b.RefillDuration(); dur != 20*time.Second {
+		// Should be 20s as (2000-1000)/50 = 20.
+		t.Errorf("Balance.RefillDuration() = %v; want %v", dur, 20*time.Second)
+	}
+}
+
+// TestSync should update the remaining points, limit, and refill rate
+// from a single observation.
+func TestSync(t *testing.T) {
+	b := newBalance()
+	b.Sync(1500, 2000, 100)
+	if b.Remaining.Load() != 1500 || b.Limit != 2000 || b.RefillRate != 100 {
+		t.Errorf("Balance = %d, %d, %v; want 1500, 2000, 100", b.Remaining.Load(), b.Limit, b.RefillRate)
+	}
+
+	// Unknown limit and refill rate are left unchanged.
+	b.Sync(1800, 0, 0)
+	if b.Remaining.Load() != 1800 || b.Limit != 2000 || b.RefillRate != 100 {
+		t.Errorf("Balance = %d, %d, %v; want 1800, 2000, 100", b.Remaining.Load(), b.Limit, b.RefillRate)
+	}
+}
+
+// TestLimitsFunc should be called once Sync changes the limit or refill rate.
+func TestLimitsFunc(t *testing.T) {
+	var calls []float64
+	b := NewBalance(100, 1000, 50, WithLimitsFunc(func(lim int32, rr float64) {
+		calls = append(calls, float64(lim), rr)
+	}))
+	b.Sync(900, 1000, 50)
+	b.Sync(1500, 2000, 100)
+	b.Sync(1800, 0, 0)
+	if len(calls) != 2 || calls[0] != 2000 || calls[1] != 100 {
+		t.Errorf("LimitsFunc calls = %v; want [2000 100]", calls)
+	}
+}
+
+// TestSyncZeroBalance should not panic without a LimitsFunc, such as for a
+// Balance decoded from JSON.
+func TestSyncZeroBalance(t *testing.T) {
+	var b Balance
+	if err := json.Unmarshal([]byte(`{"remaining":500,"limit":1000,"refill_rate":50}`), &b); err != nil {
+		t.Fatalf("json.Unmarshal() = %v; want nil", err)
+	}
+	b.SyncCost(Cost{ThrottleStatus: ThrottleStatus{MaximumAvailable: 2000, CurrentlyAvailable: 1900, RestoreRate: 100}})
+	if b.Limit != 2000 || b.RefillRate != 100 {
+		t.Errorf("Balance = %d, %v; want 2000, 100", b.Limit, b.RefillRate)
+	}
+}
+
+// TestNewBalancePct should express the threshold as a fraction of
+// the limit, following the limit as it changes.
+func TestNewBalancePct(t *testing.T) {
+	b := NewBalancePct(0.1, 1000, 100)
+	if b.Threshold != 100 {
+		t.Errorf("Balance.Threshold = %d; want 100", b.Threshold)
+	}
+
+	b.SetLimit(2000)
+	if b.Threshold != 200 {
+		t.Errorf("Balance.Threshold = %d; want 200", b.Threshold)
+	}
+	b.Sync(5000, 10000, 500)
+	if b.Threshold != 1000 {
+		t.Errorf("Balance.Threshold = %d; want 1000", b.Threshold)
+	}
+
+	// Absolute threshold no longer follows the limit.
+	b.SetThreshold(50)
+	b.SetLimit(1000)
+	if b.Threshold != 50 {
+		t.Errorf("Balance.Threshold = %d; want 50", b.Threshold)
+	}
+}
+
+// TestWithInitialRemaining should start the remaining points at the
+// initial value instead of the limit.
+func TestWithInitialRemaining(t *testing.T) {
+	b := NewBalance(100, 1000, 100, WithInitialRemaining(400))
+	if pts := b.Remaining.Load(); pts != 400 {
+		t.Errorf("Balance.Remaining = %d; want 400", pts)
+	}
+	if dur := b.RefillDuration(); dur < 5990*time.Millisecond || dur > 6*time.Second {
+		// Should be 6s as (1000-400)/100 = 6.
+		t.Errorf("Balance.RefillDuration() = %v; want 6s", dur)
+	}
+}
+
+// TestWithReserve should keep the floor of points untouched.
+func TestWithReserve(t *testing.T) {
+	b := NewBalance(100, 1000, 0, WithReserve(200))
+	b.Update(350)
+	if b.AtThreshold() {
+		t.Errorf("Balance.AtThreshold() = true; want false above 300")
+	}
+	b.Update(300)
+	if !b.AtThreshold() {
+		t.Errorf("Balance.AtThreshold() = false; want true at 300")
+	}
+
+	if _, ok := b.Reserve(150); ok {
+		t.Errorf("Balance.Reserve(150) = _, true; want false using the floor")
+	}
+	if _, ok := b.Reserve(100); !ok {
+		t.Errorf("Balance.Reserve(100) = _, false; want true")
+	}
+}
+
+// TestNewBalanceE should reject point information which would not work.
+func TestNewBalanceE(t *testing.T) {
+	tests := []struct {
+		thld  int32
+		max   int32
+		rr    float64
+		exerr error
+	}{
+		{100, 1000, 50, nil},
+		{100, 0, 50, ErrInvalidLimit},
+		{100, 1000, 0, ErrInvalidRefillRate},
+		{1000, 1000, 50, ErrInvalidThreshold},
+	}
+	for _, tt := range tests {
+		b, err := NewBalanceE(tt.thld, tt.max, tt.rr)
+		if !errors.Is(err, tt.exerr) || (err == nil) != (b != nil) {
+			t.Errorf("NewBalanceE(%d, %d, %v) = %v, %v; want %v", tt.thld, tt.max, tt.rr, b, err, tt.exerr)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package shopifysemaphore
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// ErrUnknownBucket is returned by Buckets when no Balance is set for a name.
+var ErrUnknownBucket = errors.New("shopifysemaphore: unknown bucket")
+
+// Buckets manages several named Balances, such as "graphql", "rest", and
+// "storefront", for an app which talks to several independently limited APIs.
+// It is safe for concurrent use.
+type Buckets struct {
+	mu       sync.RWMutex        // For handling the balances.
+	balances map[string]*Balance // Balances, by name.
+}
+
+// NewBuckets returns a pointer to Buckets, holding no Balances.
+func NewBuckets() *Buckets {
+	return &Buckets{balances: make(map[string]*Balance)}
+}
+
+// Set will set the Balance (b) for the name, replacing any existing Balance.
+func (bs *Buckets) Set(name string, b *Balance) {
+	defer bs.mu.Unlock()
+	bs.mu.Lock()
+	bs.balances[name] = b
+}
+
+// Get returns the Balance for the name, and false if there is none.
+func (bs *Buckets) Get(name string) (*Balance, bool) {
+	defer bs.mu.RUnlock()
+	bs.mu.RLock()
+	b, ok := bs.balances[name]
+	return b, ok
+}
+
+// Update accepts a new value of remaining points to store for the Balance
+// of the name, in the same way as Balance.Update. It will return an error
+// of ErrUnknownBucket if there is no Balance for the name.
+func (bs *Buckets) Update(name string, points int32) error {
+	b, ok := bs.Get(name)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownBucket, name)
+	}
+	b.Update(points)
+	return nil
+}
+
+// Names returns the names of every Balance, sorted.
+func (bs *Buckets) Names() []string {
+	defer bs.mu.RUnlock()
+	bs.mu.RLock()
+	names := make([]string, 0, len(bs.balances))
+	for name := range bs.balances {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
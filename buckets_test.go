@@ -0,0 +1,33 @@
+package shopifysemaphore
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// TestBuckets should update the Balance of each name independently.
+func TestBuckets(t *testing.T) {
+	bs := NewBuckets()
+	bs.Set("rest", NewBalance(4, 40, 2))
+	bs.Set("graphql", newBalance())
+
+	if err := bs.Update("graphql", 500); err != nil {
+		t.Errorf("Update(graphql, 500) = %v; want nil", err)
+	}
+	if err := bs.Update("storefront", 500); !errors.Is(err, ErrUnknownBucket) {
+		t.Errorf("Update(storefront, 500) = %v; want %v", err, ErrUnknownBucket)
+	}
+
+	gql, _ := bs.Get("graphql")
+	rest, _ := bs.Get("rest")
+	if gql.Remaining.Load() != 500 || rest.Remaining.Load() != 40 {
+		t.Errorf("Remaining = %d, %d; want 500, 40", gql.Remaining.Load(), rest.Remaining.Load())
+	}
+	if _, ok := bs.Get("storefront"); ok {
+		t.Errorf("Get(storefront) = _, true; want false")
+	}
+	if names := bs.Names(); !slices.Equal(names, []string{"graphql", "rest"}) {
+		t.Errorf("Names() = %v; want [graphql rest]", names)
+	}
+}
@@ -0,0 +1,114 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUseBulkOperation is matched, through errors.Is, by the BulkError
+// returned by CheckBudget and AcquireCost when the cost is over the budget
+// set by WithBulkBudget.
+var ErrUseBulkOperation = errors.New("shopifysemaphore: cost over budget, use a bulk operation")
+
+// BulkError is returned by CheckBudget and AcquireCost when the estimated
+// cost of a query, or of every page of a paginated loop, is over the budget
+// set by WithBulkBudget. Such work is better done as a Bulk Operation, which
+// Shopify runs without spending the point balance, such as through
+// SubmitBulkOperation.
+type BulkError struct {
+	Cost   int32 // Estimated cost which was over the budget.
+	Budget int32 // Budget set by WithBulkBudget.
+}
+
+// Error returns the error message, including the cost and budget.
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("%s: cost %d, budget %d", ErrUseBulkOperation, e.Cost, e.Budget)
+}
+
+// Unwrap returns ErrUseBulkOperation so the error can be matched with errors.Is.
+func (e *BulkError) Unwrap() error {
+	return ErrUseBulkOperation
+}
+
+// CheckBudget returns a BulkError, and runs the callback set by
+// WithBulkBudget, if the estimated cost is over the budget, otherwise nil.
+// For a paginated loop, pass the cost of a page times the number of pages.
+func (sem *Semaphore) CheckBudget(cost int32) error {
+	sem.mu.Lock()
+	budget, fn := sem.bulkBudget, sem.bulkFunc
+	sem.mu.Unlock()
+	if budget <= 0 || cost <= budget {
+		return nil
+	}
+	err := &BulkError{Cost: cost, Budget: budget}
+	sem.hook("BulkFunc", func() { fn(*err) })
+	return err
+}
+
+// WithBulkBudget is a functional option for Semaphore which will advise a
+// Bulk Operation once the estimated cost of a query passed to CheckBudget or
+// AcquireCost is over the budget (pts), calling the function (fn) with the
+// BulkError, and rejecting the query with it.
+func WithBulkBudget(pts int32, fn func(BulkError)) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		if fn == nil {
+			fn = func(_ BulkError) {}
+		}
+		sem.bulkBudget = pts
+		sem.bulkFunc = fn
+	}
+}
+
+// bulkOperationRunQuery is the mutation run by SubmitBulkOperation.
+const bulkOperationRunQuery = `mutation bulkOperationRunQuery($query: String!) {
+  bulkOperationRunQuery(query: $query) {
+    bulkOperation { id status }
+    userErrors { field message }
+  }
+}`
+
+// SubmitBulkOperation will submit the query as a Bulk Operation through the
+// bulkOperationRunQuery mutation, to the GraphQL Admin API endpoint, such as
+// https://example.myshopify.com/admin/api/2024-01/graphql.json, with the
+// access token (token), through the Doer (d), or http.DefaultClient if nil.
+// It returns the ID of the Bulk Operation, or an error including any user
+// errors of the mutation.
+func SubmitBulkOperation(ctx context.Context, d Doer, endpoint string, token string, query string) (string, error) {
+	resp, err := postGraphQL(ctx, d, endpoint, token, bulkOperationRunQuery, map[string]any{"query": query})
+	if err != nil {
+		return "", fmt.Errorf("shopifysemaphore: bulk operation: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var payload struct {
+		Errors []GraphQLError `json:"errors"`
+		Data   struct {
+			BulkOperationRunQuery struct {
+				BulkOperation *struct {
+					ID string `json:"id"`
+				} `json:"bulkOperation"`
+				UserErrors []struct {
+					Message string `json:"message"`
+				} `json:"userErrors"`
+			} `json:"bulkOperationRunQuery"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", err
+	}
+	run := payload.Data.BulkOperationRunQuery
+	var msgs []string
+	for _, e := range payload.Errors {
+		msgs = append(msgs, e.Message)
+	}
+	for _, e := range run.UserErrors {
+		msgs = append(msgs, e.Message)
+	}
+	if len(msgs) > 0 || run.BulkOperation == nil {
+		return "", fmt.Errorf("shopifysemaphore: bulk operation: %s", strings.Join(msgs, "; "))
+	}
+	return run.BulkOperation.ID, nil
+}
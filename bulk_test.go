@@ -0,0 +1,77 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestBulkBudget should advise a Bulk Operation once the cost is over the
+// budget.
+func TestBulkBudget(t *testing.T) {
+	ctx := context.Background()
+	var advised []BulkError
+	sema := NewSemaphore(1, NewStandardGraphQLBalance(), WithBulkBudget(500, func(be BulkError) {
+		advised = append(advised, be)
+	}))
+	if err := sema.CheckBudget(500); err != nil {
+		t.Errorf("CheckBudget(500) = %v; want nil", err)
+	}
+
+	// Paginated loop of 20 pages at 50 points each.
+	err := sema.CheckBudget(20 * 50)
+	var be *BulkError
+	if !errors.As(err, &be) || !errors.Is(err, ErrUseBulkOperation) || be.Cost != 1000 || be.Budget != 500 {
+		t.Errorf("CheckBudget(1000) = %v; want %v", err, &BulkError{Cost: 1000, Budget: 500})
+	}
+	if _, err := sema.AcquireCost(ctx, 600); !errors.Is(err, ErrUseBulkOperation) {
+		t.Errorf("AcquireCost(%q, 600) = %v; want %v", ctx, err, ErrUseBulkOperation)
+	}
+	if len(advised) != 2 || advised[1].Cost != 600 {
+		t.Errorf("BulkFunc calls = %+v; want 2, the last of 600", advised)
+	}
+	if st := sema.Stats(); st.Acquisitions != 0 {
+		t.Errorf("Stats().Acquisitions = %d; want 0", st.Acquisitions)
+	}
+
+	if err := newSemaphore(1).CheckBudget(1 << 20); err != nil {
+		t.Errorf("CheckBudget() = %v; want nil without a budget", err)
+	}
+}
+
+// TestSubmitBulkOperation should submit the query through the mutation and
+// return the ID of the Bulk Operation.
+func TestSubmitBulkOperation(t *testing.T) {
+	ctx := context.Background()
+	query := `{ products { edges { node { id } } } }`
+	body := `{"data":{"bulkOperationRunQuery":{"bulkOperation":{"id":"gid://shopify/BulkOperation/1","status":"CREATED"},"userErrors":[]}}}`
+	d := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		var payload struct {
+			Query     string            `json:"query"`
+			Variables map[string]string `json:"variables"`
+		}
+		json.NewDecoder(req.Body).Decode(&payload)
+		if !strings.Contains(payload.Query, "bulkOperationRunQuery") || payload.Variables["query"] != query {
+			t.Errorf("request = %+v; want bulkOperationRunQuery of %q", payload, query)
+		}
+		if tok := req.Header.Get("X-Shopify-Access-Token"); tok != "token" {
+			t.Errorf("X-Shopify-Access-Token = %q; want %q", tok, "token")
+		}
+		return &http.Response{StatusCode: 200, Status: "200 OK", Body: io.NopCloser(strings.NewReader(body))}, nil
+	})}
+
+	endpoint := "https://example.myshopify.com/admin/api/2024-01/graphql.json"
+	id, err := SubmitBulkOperation(ctx, d, endpoint, "token", query)
+	if err != nil || id != "gid://shopify/BulkOperation/1" {
+		t.Errorf("SubmitBulkOperation() = %q, %v; want %q, nil", id, err, "gid://shopify/BulkOperation/1")
+	}
+
+	body = `{"data":{"bulkOperationRunQuery":{"bulkOperation":null,"userErrors":[{"field":["query"],"message":"A bulk query operation is already in progress."}]}}}`
+	if _, err := SubmitBulkOperation(ctx, d, endpoint, "token", query); err == nil || !strings.Contains(err.Error(), "already in progress") {
+		t.Errorf("SubmitBulkOperation() = %v; want user error", err)
+	}
+}
@@ -0,0 +1,76 @@
+package shopifysemaphore
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	calibrateSamples = 8                      // Number of recent samples the refill rate is estimated from.
+	calibrateMin     = 4                      // Minimum number of samples before correcting the refill rate.
+	calibrateSpan    = 100 * time.Millisecond // Minimum span between updates for a sample, reducing noise.
+)
+
+// calibrator estimates the actual refill rate from successive updates of the
+// remaining points. Points known to be consumed in between, through Consume
+// or Reserve, are added back to a sample. Only updates where the points then
+// increased, without reaching the limit, are sampled. Any points consumed
+// elsewhere only lower a sample, so the highest recent sample is the best
+// estimate of the refill rate.
+type calibrator struct {
+	tol     float64                   // Fraction the estimate must drift from the refill rate by to correct it.
+	pts     int32                     // Remaining points of the last update.
+	used    int32                     // Points known to be consumed since the last update, less any refunded.
+	at      time.Time                 // When the last update was, zero if never.
+	samples [calibrateSamples]float64 // Recent samples of points refilled per second, as a ring.
+	n       int                       // Number of samples taken.
+}
+
+// observe will sample the remaining points (pts) at now, against the limit
+// (lim), returning the estimated refill rate if it has drifted from the
+// refill rate (rr) by more than the tolerance, or 0 if it has not.
+func (c *calibrator) observe(pts int32, lim int32, rr float64, now time.Time) float64 {
+	last, at := c.pts, c.at
+	if !at.IsZero() && now.Sub(at) < calibrateSpan {
+		// Too close to the last sample, keep comparing against it.
+		return 0
+	}
+	used := c.used
+	c.pts, c.at, c.used = pts, now, 0
+	if at.IsZero() || pts+used <= last || pts >= lim {
+		// Nothing to compare against, points consumed, or capped at the limit.
+		return 0
+	}
+
+	c.samples[c.n%calibrateSamples] = float64(pts+used-last) / now.Sub(at).Seconds()
+	c.n += 1
+	if c.n < calibrateMin {
+		return 0
+	}
+
+	var est float64
+	for _, s := range c.samples[:min(c.n, calibrateSamples)] {
+		est = max(est, s)
+	}
+	if rr > 0 && math.Abs(est-rr)/rr <= c.tol {
+		return 0
+	}
+	return est
+}
+
+// spend will record points (pts) known to be consumed since the last update,
+// or refunded if negative, to be added back to the next sample.
+func (c *calibrator) spend(pts int32) {
+	c.used += pts
+}
+
+// WithCalibration is a functional option for Balance which will estimate the
+// actual refill rate from successive updates of the remaining points, and
+// correct the RefillRate once the estimate drifts from it by more than the
+// tolerance (tol), such as 0.2 for 20%. This keeps pauses accurate when the
+// configured refill rate is wrong, such as after a plan change.
+func WithCalibration(tol float64) func(*Balance) {
+	return func(b *Balance) {
+		b.calib = &calibrator{tol: tol}
+	}
+}
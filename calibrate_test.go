@@ -0,0 +1,68 @@
+package shopifysemaphore
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCalibrator should estimate the refill rate from increases in the
+// remaining points, ignoring decreases and updates at the limit.
+func TestCalibrator(t *testing.T) {
+	c := &calibrator{tol: 0.2}
+	now := time.Now()
+	pts := int32(100)
+	c.observe(pts, 1000, 50, now)
+
+	// Refilling at 100/s, with some consumed in between, against a
+	// configured refill rate of 50/s.
+	var est float64
+	for i, refilled := range []int32{80, 100, 60, 90, 100} {
+		now = now.Add(time.Second)
+		pts += refilled
+		if est = c.observe(pts, 1000, 50, now); i < calibrateMin-1 && est != 0 {
+			t.Errorf("observe() = %v; want 0 before %d samples", est, calibrateMin)
+		}
+	}
+	if est != 100 {
+		t.Errorf("observe() = %v; want 100", est)
+	}
+
+	// Within the tolerance, no correction.
+	now = now.Add(time.Second)
+	if est := c.observe(pts+90, 1000, 90, now); est != 0 {
+		t.Errorf("observe() = %v; want 0", est)
+	}
+}
+
+// TestCalibratorConsumed should add back points consumed between updates,
+// through Consume, instead of underestimating the refill rate.
+func TestCalibratorConsumed(t *testing.T) {
+	b := NewBalance(0, 1_000_000, 10, WithCalibration(0.2))
+	var pts int32
+	for i := 0; i <= calibrateMin; i += 1 {
+		// Pretend the last update was 1s ago, with 50 points refilled and 4
+		// consumed since.
+		b.calib.at = b.calib.at.Add(-time.Second)
+		b.Update(pts)
+		b.Consume(4)
+		pts += 50 - 4
+	}
+	if rr := b.refillRate(); rr < 49 || rr > 51 {
+		t.Errorf("Balance.RefillRate = %v; want near 50", rr)
+	}
+}
+
+// TestWithCalibration should correct the refill rate of the Balance.
+func TestWithCalibration(t *testing.T) {
+	b := NewBalance(0, 1_000_000, 10, WithCalibration(0.2))
+	var pts int32
+	for i := 0; i <= calibrateMin; i += 1 {
+		// Pretend the last update was 1s ago, with 50 points refilled since.
+		b.calib.at = b.calib.at.Add(-time.Second)
+		b.Update(pts)
+		pts += 50
+	}
+	if rr := b.refillRate(); rr < 49 || rr > 51 {
+		t.Errorf("Balance.RefillRate = %v; want near 50", rr)
+	}
+}
@@ -0,0 +1,76 @@
+package shopifysemaphore
+
+import "context"
+
+// chain is a Limiter which must satisfy every one of its limiters.
+type chain []Limiter
+
+// Chain returns a Limiter which will acquire from every limiter, in order,
+// before the Goroutine can run. Such as a concurrency cap, a point balance,
+// and a requests-per-second ceiling each with their own Semaphore. If any
+// limiter fails to acquire, those already acquired are released in reverse order
+// without updating their point balance. Releasing will release every limiter
+// together, in reverse order. As acquiring always happens in the same order,
+// chains sharing limiters can not deadlock each other.
+func Chain(limiters ...Limiter) Limiter {
+	return chain(limiters)
+}
+
+// Acquire will acquire a spot from every limiter in order.
+func (c chain) Acquire(ctx context.Context) error {
+	for i, l := range c {
+		if err := l.Acquire(ctx); err != nil {
+			// Give back what was already acquired.
+			for j := i - 1; j >= 0; j -= 1 {
+				c[j].Release(ErrPts)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Release will release a spot from every limiter in reverse order.
+func (c chain) Release(pts int32) {
+	for i := len(c) - 1; i >= 0; i -= 1 {
+		c[i].Release(pts)
+	}
+}
+
+// ReleaseWithErr will release a spot from every limiter in reverse order,
+// for a failed request.
+func (c chain) ReleaseWithErr(err error) {
+	for i := len(c) - 1; i >= 0; i -= 1 {
+		c[i].ReleaseWithErr(err)
+	}
+}
+
+// Stats returns a combined snapshot of every limiter. InFlight, Capacity and
+// Acquisitions are taken from the first limiter, which every spot passes
+// through. Waiters, Pauses, PausedFor and Failures are summed, Paused is true
+// if any limiter is paused, Remaining is the lowest reported, and LastErr is
+// the first one found.
+func (c chain) Stats() Stats {
+	var st Stats
+	for i, l := range c {
+		ls := l.Stats()
+		if i == 0 {
+			st.InFlight = ls.InFlight
+			st.Capacity = ls.Capacity
+			st.Acquisitions = ls.Acquisitions
+			st.Remaining = ls.Remaining
+		}
+		if ls.Remaining < st.Remaining {
+			st.Remaining = ls.Remaining
+		}
+		if st.LastErr == nil {
+			st.LastErr = ls.LastErr
+		}
+		st.Waiters += ls.Waiters
+		st.Pauses += ls.Pauses
+		st.PausedFor += ls.PausedFor
+		st.Failures += ls.Failures
+		st.Paused = st.Paused || ls.Paused
+	}
+	return st
+}
@@ -0,0 +1,39 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestChain should acquire from every limiter and release them together.
+func TestChain(t *testing.T) {
+	ctx := context.Background()
+	s1 := newSemaphore(2)
+	s2 := newSemaphore(1)
+	c := Chain(s1, s2)
+
+	if err := c.Acquire(ctx); err != nil {
+		t.Fatalf("Chain.Acquire(%q) = %v; want nil", ctx, err)
+	}
+	if st1, st2 := s1.Stats(), s2.Stats(); st1.InFlight != 1 || st2.InFlight != 1 {
+		t.Errorf("InFlight = %d, %d; want 1, 1", st1.InFlight, st2.InFlight)
+	}
+
+	// Second limiter is full, first limiter should be given back.
+	tctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := c.Acquire(tctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Chain.Acquire(%q) = %v; want %v", tctx, err, context.DeadlineExceeded)
+	}
+	if st := s1.Stats(); st.InFlight != 1 {
+		t.Errorf("InFlight = %d; want 1", st.InFlight)
+	}
+
+	c.Release(950)
+	st := c.Stats()
+	if st.InFlight != 0 || st.Remaining != 950 {
+		t.Errorf("Chain.Stats() = %+v; want 0 in flight and 950 remaining", st)
+	}
+}
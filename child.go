@@ -0,0 +1,30 @@
+package shopifysemaphore
+
+// Child returns a pointer to a Semaphore which shares the point balance of
+// the parent, but receives only a fraction of the parent's cap, such as 0.5
+// for half. Acquiring from the child will also acquire from the parent, and
+// releasing to the child will release to the parent, which remains responsible
+// for updating the point balance and pausing. This allows several workloads
+// against the same shop, such as product and inventory syncs, to share one
+// point balance without one starving the other of spots. The child is bound
+// to the lifecycle of the parent and accepts optional parameters of its own.
+func (sem *Semaphore) Child(fraction float64, opts ...func(*Semaphore)) *Semaphore {
+	cap := 0
+	if sem.cap > 0 {
+		cap = max(1, int(float64(sem.cap)*fraction))
+	}
+	child := newSemaphoreFrom(sem.ctx, cap, sem.model, opts...)
+	child.parent = sem
+	return child
+}
+
+// releaseChild will release a spot held on a child, leaving the point
+// balance and pausing to the parent.
+func (sem *Semaphore) releaseChild(err error, leased bool) {
+	defer sem.mu.Unlock()
+	sem.mu.Lock()
+
+	sem.checkHeld(leased)
+	sem.record(err)
+	sem.free(leased)
+}
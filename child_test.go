@@ -0,0 +1,63 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestChild should limit the child to its share of the parent's cap,
+// while still counting against the parent and its point balance.
+func TestChild(t *testing.T) {
+	ctx := context.Background()
+	sema := newSemaphore(4)
+	child := sema.Child(0.5)
+
+	for i := 0; i < 2; i += 1 {
+		if err := child.Acquire(ctx); err != nil {
+			t.Fatalf("Child.Acquire(%q) = %v; want nil", ctx, err)
+		}
+	}
+
+	// Child is at its share, parent still has room.
+	tctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := child.Acquire(tctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Child.Acquire(%q) = %v; want %v", tctx, err, context.DeadlineExceeded)
+	}
+	if st := sema.Stats(); st.InFlight != 2 {
+		t.Errorf("Stats().InFlight = %d; want 2", st.InFlight)
+	}
+
+	child.Release(950)
+	if st := sema.Stats(); st.InFlight != 1 || st.Remaining != 950 {
+		t.Errorf("Stats() = %+v; want 1 in flight and 950 remaining", st)
+	}
+	if st := child.Stats(); st.InFlight != 1 {
+		t.Errorf("Child.Stats().InFlight = %d; want 1", st.InFlight)
+	}
+}
+
+// TestChildLeaseExpire should release the spot on both the child
+// and the parent when a lease on the child expires.
+func TestChildLeaseExpire(t *testing.T) {
+	expired := make(chan bool, 1)
+
+	ctx := context.Background()
+	sema := newSemaphore(2)
+	child := sema.Child(0.5, WithLeaseTTL(10*time.Millisecond), WithLeaseExpiredFunc(func(_ *Lease) {
+		expired <- true
+	}))
+
+	if _, err := child.AcquireLease(ctx); err != nil {
+		t.Fatalf("Child.AcquireLease(%q) = %v; want nil", ctx, err)
+	}
+	<-expired
+	if st := sema.Stats(); st.InFlight != 0 || !errors.Is(st.LastErr, ErrLeaseExpired) {
+		t.Errorf("Stats() = %+v; want 0 in flight and %v", st, ErrLeaseExpired)
+	}
+	if st := child.Stats(); st.InFlight != 0 {
+		t.Errorf("Child.Stats().InFlight = %d; want 0", st.InFlight)
+	}
+}
@@ -0,0 +1,78 @@
+package shopifysemaphore
+
+import "time"
+
+// breaker holds the state of the circuit breaker set by WithCircuitBreaker.
+type breaker struct {
+	trips  int           // Number of events within the window which open the circuit.
+	window time.Duration // Window the events are counted within.
+	open   time.Duration // Duration the circuit stays open for.
+
+	events []time.Time // When the events within the window happened, oldest first.
+	until  time.Time   // When the circuit closes, zero if never opened.
+}
+
+// observe will record an event, a pause or throttled request, at now and
+// return true if the circuit should open, clearing the events if so.
+func (br *breaker) observe(now time.Time) bool {
+	i := 0
+	for i < len(br.events) && now.Sub(br.events[i]) > br.window {
+		i += 1
+	}
+	br.events = append(br.events[i:], now)
+	if len(br.events) < br.trips {
+		return false
+	}
+	br.events = br.events[:0]
+	return true
+}
+
+// circuitOpen returns true if the circuit breaker is open, holding the
+// Semaphore paused regardless of the point balance.
+// It must be called while holding mu.
+func (sem *Semaphore) circuitOpen() bool {
+	return sem.breaker != nil && time.Now().Before(sem.breaker.until)
+}
+
+// evalCircuit will record a pause or throttled request (err) against the
+// circuit breaker, given whether a pause just happened (paused), and open
+// the circuit if it trips. Opening the circuit pauses for the open duration,
+// or extends the current pause to it, and runs the CircuitOpenFunc.
+// It must be called while holding mu.
+func (sem *Semaphore) evalCircuit(pts int32, paused bool, throttled bool) {
+	if sem.breaker == nil || (!paused && !throttled) || sem.circuitOpen() {
+		return
+	}
+	now := time.Now()
+	if !sem.breaker.observe(now) {
+		return
+	}
+
+	dur := sem.breaker.open
+	sem.breaker.until = now.Add(dur)
+	sem.hold(pts, dur, PauseCircuitOpen)
+	info := sem.pauseInfo(pts, dur, PauseCircuitOpen)
+	fn := sem.CircuitOpenFunc
+	go sem.hook("CircuitOpenFunc", func() { fn(info) })
+}
+
+// WithCircuitBreaker is a functional option for Semaphore which will open
+// a circuit, pausing for the open duration, once a number (n) of pauses or
+// requests released with ErrThrottled happen within the window. This stops
+// a configuration which oscillates between pausing and resuming endlessly.
+// While open, the pause is not resumed early, even if the point balance
+// recovers.
+func WithCircuitBreaker(n int, window time.Duration, open time.Duration) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.breaker = &breaker{trips: max(n, 1), window: window, open: open}
+	}
+}
+
+// WithCircuitOpenFunc is a functional option for Semaphore to call when
+// the circuit breaker opens. A PauseInfo, with the PauseCircuitOpen reason
+// and the open duration, will be passed into the function.
+func WithCircuitOpenFunc(fn func(PauseInfo)) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.CircuitOpenFunc = fn
+	}
+}
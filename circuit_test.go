@@ -0,0 +1,61 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestCircuitBreaker should open the circuit once enough pauses or
+// throttled requests happen within the window, holding the pause open.
+func TestCircuitBreaker(t *testing.T) {
+	opened := make(chan PauseInfo, 1)
+	resumed := make(chan ResumeInfo, 1)
+	ctx := context.Background()
+	sema := newSemaphore(1, WithMaxPause(time.Millisecond), WithCircuitBreaker(2, time.Second, 50*time.Millisecond), WithCircuitOpenFunc(func(info PauseInfo) {
+		opened <- info
+	}), WithResumeFuncInfo(func(info ResumeInfo) {
+		resumed <- info
+	}))
+
+	// First pause, the circuit stays closed.
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(0)
+	<-resumed
+	if st := sema.Stats(); st.CircuitOpen {
+		t.Errorf("Stats().CircuitOpen = true; want false")
+	}
+
+	// Throttled while recovered, opens the circuit.
+	start := time.Now()
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(1000)
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.ReleaseWithErr(ErrThrottled)
+	select {
+	case info := <-opened:
+		if info.Reason != PauseCircuitOpen || info.Duration != 50*time.Millisecond {
+			t.Errorf("CircuitOpenFunc(%+v); want Reason %v, Duration 50ms", info, PauseCircuitOpen)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("CircuitOpenFunc not called; want called")
+	}
+	if st := sema.Stats(); !st.CircuitOpen || !st.Paused {
+		t.Errorf("Stats() = %+v; want circuit open and paused", st)
+	}
+
+	// Held for the open duration, even though the balance is above the threshold.
+	info := <-resumed
+	if info.Reason != PauseCircuitOpen {
+		t.Errorf("ResumeInfo.Reason = %v; want %v", info.Reason, PauseCircuitOpen)
+	}
+	if dur := time.Since(start); dur < 50*time.Millisecond {
+		t.Errorf("resumed after %v; want at least 50ms", dur)
+	}
+}
@@ -0,0 +1,58 @@
+package shopifysemaphore
+
+import "net/http"
+
+// NewClient returns a copy of the HTTP client (base), or of
+// http.DefaultClient if nil, with its transport wrapped in a Transport
+// regulated by the Semaphore (sem). This suits SDKs which accept an
+// *http.Client, such as github.com/bold-commerce/go-shopify through its
+// WithHTTPClient option, giving them pausing behavior without changing how
+// they are called. Only the responses are read, so an SDK's own rate limit
+// information and retries are left as they are. This includes GraphQL
+// clients such as
+// github.com/shurcooL/graphql through its NewClient, and
+// github.com/machinebox/graphql through its WithHTTPClient option.
+func NewClient(sem *Semaphore, base *http.Client) *http.Client {
+	if base == nil {
+		base = http.DefaultClient
+	}
+	client := *base
+	client.Transport = NewTransport(sem, base.Transport)
+	return &client
+}
+
+// Doer represents a client which makes HTTP requests, as expected by GraphQL
+// clients such as github.com/Khan/genqlient, and satisfied by *http.Client.
+type Doer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// doerTransport is an http.RoundTripper which makes requests through a Doer.
+type doerTransport struct {
+	d Doer // Client making the requests.
+}
+
+// RoundTrip will make the request (req) through the Doer.
+func (dt doerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return dt.d.Do(req)
+}
+
+// regulatedDoer is a Doer which makes requests through a Transport.
+type regulatedDoer struct {
+	tr *Transport // Transport regulating the requests.
+}
+
+// Do will make the request (req) through the Transport.
+func (rd regulatedDoer) Do(req *http.Request) (*http.Response, error) {
+	return rd.tr.RoundTrip(req)
+}
+
+// NewDoer returns a Doer which makes requests through the Doer (d), or
+// http.DefaultClient if nil, regulated by the Semaphore (sem) in the same
+// way as a Transport, handling the acquire, parse, and release internally.
+func NewDoer(sem *Semaphore, d Doer) Doer {
+	if d == nil {
+		d = http.DefaultClient
+	}
+	return regulatedDoer{tr: NewTransport(sem, doerTransport{d: d})}
+}
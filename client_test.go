@@ -0,0 +1,47 @@
+package shopifysemaphore
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestNewClient should copy the client, regulating its transport.
+func TestNewClient(t *testing.T) {
+	sema := NewSemaphoreModel(1, NewStandardRESTBucket())
+	base := &http.Client{Timeout: time.Second, Transport: respond(200, HeaderCallLimit, "30/40", "{}")}
+	client := NewClient(sema, base)
+	if client == base || client.Timeout != base.Timeout {
+		t.Errorf("NewClient() = %+v; want copy of %+v", client, base)
+	}
+	if tr, ok := client.Transport.(*Transport); !ok || tr.Sem != sema || tr.Base == nil {
+		t.Fatalf("NewClient().Transport = %T; want *Transport wrapping the base", client.Transport)
+	}
+
+	resp, err := client.Get("https://example.myshopify.com/admin/api/2024-01/shop.json")
+	if err != nil {
+		t.Fatalf("Get() = %v; want nil", err)
+	}
+	resp.Body.Close()
+	if st := sema.Stats(); st.Remaining != 10 {
+		t.Errorf("Stats().Remaining = %d; want 10", st.Remaining)
+	}
+	if NewClient(sema, nil).Transport.(*Transport).Base != nil {
+		t.Error("NewClient(nil).Transport.Base != nil; want nil for http.DefaultTransport")
+	}
+}
+
+// TestNewDoer should regulate the requests made through the Doer.
+func TestNewDoer(t *testing.T) {
+	sema := NewSemaphoreModel(1, NewStandardRESTBucket())
+	d := NewDoer(sema, &http.Client{Transport: respond(200, HeaderCallLimit, "35/40", "{}")})
+	req, _ := http.NewRequest(http.MethodGet, "https://example.myshopify.com/admin/api/2024-01/shop.json", nil)
+	resp, err := d.Do(req)
+	if err != nil {
+		t.Fatalf("Do() = %v; want nil", err)
+	}
+	resp.Body.Close()
+	if st := sema.Stats(); st.InFlight != 0 || st.Remaining != 5 || st.Acquisitions != 1 {
+		t.Errorf("Stats() = %+v; want released with 5 remaining", st)
+	}
+}
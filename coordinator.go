@@ -0,0 +1,132 @@
+package shopifysemaphore
+
+import "time"
+
+// coordinate will signal the coordinator that the pause has changed, such as
+// a new pause, a hold, or an early resume, so it re-evaluates when to resume.
+// The coordinator is started on a pause, and is the only Goroutine which
+// resumes from a pause once it is over, exiting once no longer paused so a
+// Semaphore which is never paused again holds no Goroutine. It must be called
+// while holding mu.
+func (sem *Semaphore) coordinate() {
+	if sem.kick == nil {
+		sem.kick = make(chan struct{}, 1)
+		go sem.run(sem.kick)
+		return
+	}
+	select {
+	case sem.kick <- struct{}{}:
+	default:
+		// Already signalled, the coordinator has yet to re-evaluate.
+	}
+}
+
+// run is the coordinator, waiting for the current pause to be over and then
+// resuming from it, until no longer paused or the Semaphore's lifecycle ends.
+// It is signalled through the channel (kick) when the pause changes.
+func (sem *Semaphore) run(kick <-chan struct{}) {
+	t := time.NewTimer(0)
+	defer t.Stop()
+	for {
+		sem.mu.Lock()
+		wake, fn, ask := sem.step(time.Now())
+		veto, retry := sem.BeforeResume, sem.ResumeRetry
+		done := !sem.paused
+		if done {
+			// Nothing left to resume, the next pause starts a new coordinator.
+			sem.kick = nil
+		}
+		sem.mu.Unlock()
+		if fn != nil {
+			go fn()
+		}
+		if done {
+			return
+		}
+		if ask {
+			// Pause is over, ask if it can be resumed from without holding mu.
+			ok := true
+			sem.hook("BeforeResume", func() { ok = veto() })
+			if ok {
+				sem.mu.Lock()
+				sem.approved = true
+				sem.mu.Unlock()
+				continue
+			}
+			wake = retry
+		}
+
+		var timeout <-chan time.Time
+		t.Stop()
+		if wake > 0 {
+			t.Reset(wake)
+			timeout = t.C
+		}
+		select {
+		case <-timeout:
+		case <-kick:
+		case <-sem.ctx.Done():
+			// Semaphore's lifecycle has ended, unflag as paused but
+			// abandon the resume as there is nobody left to grant.
+			sem.mu.Lock()
+			if sem.paused {
+				sem.unpause()
+			}
+			sem.kick = nil
+			sem.mu.Unlock()
+			return
+		}
+	}
+}
+
+// step will evaluate the current pause as of now, resuming from it if it is
+// over. It returns how long until the pause should be evaluated again, 0 if
+// only once it changes, the resume callbacks to run if resumed, and true if
+// the pause is over but BeforeResume must first approve resuming. With a
+// PauseTick, the point balance is re-evaluated every tick, resuming as soon as
+// it has cleared, and the pause continues past its duration for as long as it
+// has not, up to any MaxPause. A pause which is held, such as by an open
+// circuit breaker, is not resumed until the hold ends.
+// It must be called while holding mu.
+func (sem *Semaphore) step(now time.Time) (time.Duration, func(), bool) {
+	if !sem.paused {
+		return 0, nil, false
+	}
+	if sem.held(now) {
+		return sem.holdUntil.Sub(now), nil, false
+	}
+
+	done := !now.Before(sem.pauseEnds)
+	wake := sem.pauseEnds.Sub(now)
+	if sem.PauseTick > 0 {
+		// Resume once cleared, even before the duration, but not while
+		// still at the threshold unless the MaxPause has been reached.
+		done = sem.cleared() || (sem.MaxPause > 0 && now.Sub(sem.pausedAt) >= sem.MaxPause)
+		wake = sem.PauseTick
+	}
+	if !done {
+		return wake, nil, false
+	}
+	if sem.BeforeResume != nil && !sem.approved {
+		return 0, nil, true
+	}
+	info := sem.unpause()
+	sem.grant()
+	return 0, sem.onResume(info), false
+}
+
+// WithBeforeResume is a functional option for Semaphore which will ask the
+// function (fn) before resuming from a pause once it is over, such as an
+// external health check which knows other processes are still draining the
+// same point balance. If it returns false, the resume is delayed and it is
+// asked again after the duration (retry), or DefaultPauseBuffer if 0 or less.
+// It is not asked for an early resume through ResumeAbove or ResumeAll.
+func WithBeforeResume(fn func() bool, retry time.Duration) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		if retry <= 0 {
+			retry = DefaultPauseBuffer
+		}
+		sem.BeforeResume = fn
+		sem.ResumeRetry = retry
+	}
+}
@@ -0,0 +1,121 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestStep should resume from the pause only once it is over, and not
+// while it is held.
+func TestStep(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sema := NewSemaphoreWithContext(ctx, 1, NewBalance(900, 1000, 100))
+
+	defer sema.mu.Unlock()
+	sema.mu.Lock()
+	sema.pause(1000, time.Minute, PauseManual)
+	now := sema.pausedAt
+	if wake, fn, _ := sema.step(now.Add(20 * time.Second)); wake != 40*time.Second || fn != nil {
+		t.Errorf("step(+20s) = %v, %v; want 40s, nil", wake, fn != nil)
+	}
+
+	// Held past the duration.
+	sema.holdUntil = now.Add(2 * time.Minute)
+	if wake, fn, _ := sema.step(now.Add(time.Minute)); wake != time.Minute || fn != nil {
+		t.Errorf("step(+1m) = %v, %v; want 1m, nil", wake, fn != nil)
+	}
+	if wake, fn, _ := sema.step(now.Add(2 * time.Minute)); wake != 0 || fn == nil {
+		t.Errorf("step(+2m) = %v, %v; want 0, resume", wake, fn != nil)
+	}
+	if sema.paused {
+		t.Errorf("paused = true; want false")
+	}
+	if wake, fn, _ := sema.step(now.Add(3 * time.Minute)); wake != 0 || fn != nil {
+		t.Errorf("step(+3m) = %v, %v; want 0, nil when not paused", wake, fn != nil)
+	}
+}
+
+// TestOverlappingPauses should resume once, after the latest of
+// overlapping pauses.
+func TestOverlappingPauses(t *testing.T) {
+	resumed := make(chan time.Time, 2)
+	sema := newSemaphore(1, WithResumeFunc(func() {
+		resumed <- time.Now()
+	}))
+	start := time.Now()
+	sema.PauseFor(10 * time.Millisecond)
+	sema.mu.Lock()
+	sema.hold(1000, 50*time.Millisecond, PauseManual)
+	sema.mu.Unlock()
+
+	if dur := (<-resumed).Sub(start); dur < 50*time.Millisecond {
+		t.Errorf("resumed after %v; want at least 50ms", dur)
+	}
+	select {
+	case <-resumed:
+		t.Errorf("ResumeFunc called twice; want once")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// TestBeforeResume should delay resuming from a pause until approved.
+func TestBeforeResume(t *testing.T) {
+	resumed := make(chan time.Time, 1)
+	var asked atomic.Int32
+	sema := newSemaphore(1, WithBeforeResume(func() bool {
+		return asked.Add(1) > 2
+	}, 10*time.Millisecond), WithResumeFunc(func() {
+		resumed <- time.Now()
+	}))
+	start := time.Now()
+	sema.PauseFor(5 * time.Millisecond)
+
+	// Declined twice, 10ms apart.
+	select {
+	case at := <-resumed:
+		if dur := at.Sub(start); dur < 25*time.Millisecond {
+			t.Errorf("resumed after %v; want at least 25ms", dur)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("ResumeFunc not called; want called")
+	}
+	if n := asked.Load(); n != 3 {
+		t.Errorf("BeforeResume called %d times; want 3", n)
+	}
+}
+
+// TestCoordinatorExits should leave no coordinator running once resumed,
+// and start a new one on the next pause.
+func TestCoordinatorExits(t *testing.T) {
+	base := runtime.NumGoroutine()
+	sems := make([]*Semaphore, 50)
+	for i := range sems {
+		sems[i] = newSemaphore(1)
+		sems[i].PauseFor(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	n := base
+	for range 20 {
+		if n = runtime.NumGoroutine(); n <= base+2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n > base+2 {
+		t.Errorf("NumGoroutine() = %d; want %d after resuming", n, base)
+	}
+
+	// Paused again, a new coordinator resumes.
+	resumed := make(chan struct{}, 1)
+	sems[0].SetResumeFunc(func() { resumed <- struct{}{} })
+	sems[0].PauseFor(10 * time.Millisecond)
+	select {
+	case <-resumed:
+	case <-time.After(time.Second):
+		t.Error("ResumeFunc not called; want called after the second pause")
+	}
+}
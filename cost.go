@@ -0,0 +1,57 @@
+package shopifysemaphore
+
+import "errors"
+
+// ErrNoCost is returned by ParseGraphQLCost when the response body has no
+// cost extension, such as for an error page or a non-GraphQL response.
+var ErrNoCost = errors.New("shopifysemaphore: response has no cost extension")
+
+// ThrottleStatus represents the throttleStatus of the GraphQL Admin API's
+// cost extension, reporting the point balance after the query.
+type ThrottleStatus struct {
+	MaximumAvailable   float64 `json:"maximumAvailable"`   // Maximum points available.
+	CurrentlyAvailable float64 `json:"currentlyAvailable"` // Points remaining.
+	RestoreRate        float64 `json:"restoreRate"`        // Number of points refilled per second.
+}
+
+// Cost represents the extensions.cost of a GraphQL Admin API response,
+// reporting what the query cost and the point balance after it.
+type Cost struct {
+	RequestedQueryCost float64        `json:"requestedQueryCost"` // Points estimated and held before the query.
+	ActualQueryCost    *float64       `json:"actualQueryCost"`    // Points the query actually cost, nil if not run, such as when throttled.
+	ThrottleStatus     ThrottleStatus `json:"throttleStatus"`     // Point balance after the query.
+}
+
+// Remaining returns the points remaining after the query as an int32, as
+// accepted by Release and Update, or ErrPts if no throttleStatus was reported.
+func (c Cost) Remaining() int32 {
+	if c.ThrottleStatus.MaximumAvailable == 0 {
+		return ErrPts
+	}
+	return int32(c.ThrottleStatus.CurrentlyAvailable)
+}
+
+// ParseGraphQLCost decodes the extensions.cost of a GraphQL Admin API
+// response body. It returns ErrNoCost if the body has no cost extension, or
+// the decoding error if the body is not JSON.
+func ParseGraphQLCost(body []byte) (Cost, error) {
+	resp, err := ParseGraphQLResponse(body)
+	if err != nil {
+		return Cost{}, err
+	}
+	if resp.Extensions.Cost == nil {
+		return Cost{}, ErrNoCost
+	}
+	return *resp.Extensions.Cost, nil
+}
+
+// SyncCost will update the remaining points, limit, and refill rate together
+// from the throttleStatus of the cost (c) through Sync, so the Balance follows
+// the shop's actual plan. A cost without a throttleStatus is ignored.
+func (b *Balance) SyncCost(c Cost) {
+	if c.Remaining() == ErrPts {
+		return
+	}
+	ts := c.ThrottleStatus
+	b.Sync(c.Remaining(), int32(ts.MaximumAvailable), ts.RestoreRate)
+}
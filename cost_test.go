@@ -0,0 +1,66 @@
+package shopifysemaphore
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestParseGraphQLCost should decode the cost extension, or error if there
+// is none.
+func TestParseGraphQLCost(t *testing.T) {
+	body := `{
+		"data": {},
+		"extensions": {
+			"cost": {
+				"requestedQueryCost": 101,
+				"actualQueryCost": 46,
+				"throttleStatus": {
+					"maximumAvailable": 1000.0,
+					"currentlyAvailable": 954.5,
+					"restoreRate": 50.0
+				}
+			}
+		}
+	}`
+	cost, err := ParseGraphQLCost([]byte(body))
+	if err != nil {
+		t.Fatalf("ParseGraphQLCost() = %v; want nil", err)
+	}
+	if cost.RequestedQueryCost != 101 || cost.ActualQueryCost == nil || *cost.ActualQueryCost != 46 {
+		t.Errorf("ParseGraphQLCost() = %+v; want requested 101 and actual 46", cost)
+	}
+	exts := ThrottleStatus{MaximumAvailable: 1000, CurrentlyAvailable: 954.5, RestoreRate: 50}
+	if cost.ThrottleStatus != exts {
+		t.Errorf("ParseGraphQLCost().ThrottleStatus = %+v; want %+v", cost.ThrottleStatus, exts)
+	}
+	if pts := cost.Remaining(); pts != 954 {
+		t.Errorf("Cost.Remaining() = %d; want 954", pts)
+	}
+
+	// Throttled, the query was not run.
+	cost, err = ParseGraphQLCost([]byte(`{"errors":[],"extensions":{"cost":{"requestedQueryCost":101,"actualQueryCost":null}}}`))
+	if err != nil || cost.ActualQueryCost != nil || cost.Remaining() != ErrPts {
+		t.Errorf("ParseGraphQLCost() = %+v, %v; want no actual cost or remaining, nil", cost, err)
+	}
+
+	if _, err := ParseGraphQLCost([]byte(`{"data":{}}`)); !errors.Is(err, ErrNoCost) {
+		t.Errorf("ParseGraphQLCost() = %v; want %v", err, ErrNoCost)
+	}
+	if _, err := ParseGraphQLCost([]byte(`Bad Gateway`)); err == nil {
+		t.Error("ParseGraphQLCost() = nil; want error")
+	}
+}
+
+// TestSyncCost should follow the throttleStatus of the cost.
+func TestSyncCost(t *testing.T) {
+	b := NewStandardGraphQLBalance()
+	b.SyncCost(Cost{})
+	if b.Remaining.Load() != 1000 {
+		t.Errorf("Balance.Remaining = %d; want 1000", b.Remaining.Load())
+	}
+
+	b.SyncCost(Cost{ThrottleStatus: ThrottleStatus{MaximumAvailable: 2000, CurrentlyAvailable: 1900, RestoreRate: 100}})
+	if b.Remaining.Load() != 1900 || b.Limit != 2000 || b.RefillRate != 100 || b.Threshold != 200 {
+		t.Errorf("Balance = %d, %d, %v, %d; want 1900, 2000, 100, 200", b.Remaining.Load(), b.Limit, b.RefillRate, b.Threshold)
+	}
+}
@@ -0,0 +1,478 @@
+// Package costestimate estimates the cost of a Shopify GraphQL Admin API
+// query before it is sent, following Shopify's documented static cost
+// heuristic, so workers can acquire cost-weighted spots ahead of the request.
+//
+// Without the schema, a field with a selection set is taken to be an object,
+// costing 1, and one with a first or last argument to be a connection,
+// costing 2 plus its size times the cost of each node. Scalars cost nothing,
+// a mutation field costs 10, and of the inline fragments of a selection, only
+// the most expensive is counted. As with Shopify's requestedQueryCost, the
+// estimate assumes every connection is full.
+package costestimate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MutationCost is the cost of each root field of a mutation.
+const MutationCost = 10
+
+// ErrSyntax is returned by Estimate when the query can not be parsed.
+var ErrSyntax = errors.New("costestimate: invalid query")
+
+// Estimate returns the estimated cost of the query, resolving the sizes of
+// connections passed as variables (vars), such as $first. A query with many
+// operations is estimated as the most expensive of them.
+func Estimate(query string, vars map[string]any) (int32, error) {
+	toks, err := lex(query)
+	if err != nil {
+		return 0, err
+	}
+	p := &parser{toks: toks, vars: vars, frags: make(map[string][]selection), spreading: make(map[string]struct{})}
+	doc, err := p.document()
+	if err != nil {
+		return 0, err
+	}
+
+	var cost int64
+	for _, op := range doc {
+		var c int64
+		if op.mutation {
+			for i := range op.sels {
+				if op.sels[i].name != "" {
+					c = sat(c + MutationCost)
+				}
+			}
+		} else {
+			c = p.cost(op.sels)
+		}
+		cost = max(cost, c)
+	}
+	return int32(cost), nil
+}
+
+// sat returns the cost (n) saturated at math.MaxInt32, so the estimate of a
+// query with deeply nested connections does not wrap around.
+func sat(n int64) int64 {
+	return min(n, math.MaxInt32)
+}
+
+// token represents a lexed token of a query, either punctuation, a name,
+// or a value.
+type token struct {
+	kind byte   // Punctuation character, or 'n' for a name, 'v' for a value.
+	text string // Text of a name or value.
+}
+
+// lex returns the tokens of the query (q), skipping whitespace, commas, and
+// comments.
+func lex(q string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(q); {
+		c := q[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i += 1
+		case c == '#':
+			for i < len(q) && q[i] != '\n' {
+				i += 1
+			}
+		case c == '.':
+			if i+2 >= len(q) || q[i+1] != '.' || q[i+2] != '.' {
+				return nil, fmt.Errorf("%w: unexpected %q", ErrSyntax, c)
+			}
+			toks = append(toks, token{kind: '.'})
+			i += 3
+		case c == '"':
+			j := i + 1
+			if len(q) >= i+3 && q[i:i+3] == `"""` {
+				end := strings.Index(q[i+3:], `"""`)
+				if end < 0 {
+					return nil, fmt.Errorf("%w: unterminated string", ErrSyntax)
+				}
+				toks = append(toks, token{kind: 'v', text: q[i+3 : i+3+end]})
+				i += 6 + end
+				continue
+			}
+			for j < len(q) && q[j] != '"' {
+				if q[j] == '\\' {
+					j += 1
+				}
+				j += 1
+			}
+			if j >= len(q) {
+				return nil, fmt.Errorf("%w: unterminated string", ErrSyntax)
+			}
+			toks = append(toks, token{kind: 'v', text: q[i+1 : j]})
+			i = j + 1
+		case isNameStart(c):
+			j := i
+			for j < len(q) && (isNameStart(q[j]) || isDigit(q[j])) {
+				j += 1
+			}
+			toks = append(toks, token{kind: 'n', text: q[i:j]})
+			i = j
+		case isDigit(c) || c == '-':
+			j := i + 1
+			for j < len(q) && (isDigit(q[j]) || q[j] == '.' || q[j] == 'e' || q[j] == 'E' || q[j] == '+' || q[j] == '-') {
+				j += 1
+			}
+			toks = append(toks, token{kind: 'v', text: q[i:j]})
+			i = j
+		case c == '{' || c == '}' || c == '(' || c == ')' || c == ':' || c == '$' ||
+			c == '!' || c == '@' || c == '=' || c == '[' || c == ']' || c == '|' || c == '&':
+			toks = append(toks, token{kind: c})
+			i += 1
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q", ErrSyntax, c)
+		}
+	}
+	return toks, nil
+}
+
+// isNameStart returns true if the character (c) may start a name.
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isDigit returns true if the character (c) is a digit.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// operation represents a parsed operation of a query.
+type operation struct {
+	mutation bool        // If the operation is a mutation.
+	sels     []selection // Root selections.
+}
+
+// selection represents a parsed field, fragment spread, or inline fragment.
+type selection struct {
+	name   string      // Name of the field, empty for a fragment.
+	size   int32       // Value of the first or last argument, 0 if not a connection.
+	sels   []selection // Selections of the field or inline fragment, nil for a scalar.
+	spread string      // Name of the spread fragment, if a fragment spread.
+	inline bool        // If an inline fragment.
+}
+
+// parser holds the state of parsing the tokens of a query.
+type parser struct {
+	toks  []token                // Tokens of the query.
+	pos   int                    // Index of the next token.
+	vars  map[string]any         // Variables of the query.
+	frags map[string][]selection // Selections of each named fragment.
+
+	spreading map[string]struct{} // Fragments being costed, to detect cycles.
+}
+
+// peek returns the kind of the next token, 0 if there are none left.
+func (p *parser) peek() byte {
+	if p.pos >= len(p.toks) {
+		return 0
+	}
+	return p.toks[p.pos].kind
+}
+
+// expect returns the next token, erroring if it is not of the kind.
+func (p *parser) expect(kind byte) (token, error) {
+	if p.peek() != kind {
+		return token{}, fmt.Errorf("%w: expected %q at token %d", ErrSyntax, kind, p.pos)
+	}
+	p.pos += 1
+	return p.toks[p.pos-1], nil
+}
+
+// document parses every operation and fragment definition.
+func (p *parser) document() ([]operation, error) {
+	var ops []operation
+	for p.peek() != 0 {
+		if p.peek() == '{' {
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, operation{sels: sels})
+			continue
+		}
+
+		kw, err := p.expect('n')
+		if err != nil {
+			return nil, err
+		}
+		switch kw.text {
+		case "fragment":
+			name, err := p.expect('n')
+			if err != nil {
+				return nil, err
+			}
+			if on, err := p.expect('n'); err != nil || on.text != "on" {
+				return nil, fmt.Errorf("%w: expected on", ErrSyntax)
+			}
+			if _, err := p.expect('n'); err != nil {
+				return nil, err
+			}
+			if err := p.directives(); err != nil {
+				return nil, err
+			}
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			p.frags[name.text] = sels
+		case "query", "mutation", "subscription":
+			if p.peek() == 'n' {
+				p.pos += 1
+			}
+			if p.peek() == '(' {
+				if err := p.skip('(', ')'); err != nil {
+					return nil, err
+				}
+			}
+			if err := p.directives(); err != nil {
+				return nil, err
+			}
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, operation{mutation: kw.text == "mutation", sels: sels})
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q", ErrSyntax, kw.text)
+		}
+	}
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("%w: no operation", ErrSyntax)
+	}
+	return ops, nil
+}
+
+// skip will skip the tokens from an opening (open) until its matching
+// closing (close) token.
+func (p *parser) skip(open byte, close byte) error {
+	depth := 0
+	for {
+		switch p.peek() {
+		case 0:
+			return fmt.Errorf("%w: expected %q", ErrSyntax, close)
+		case open:
+			depth += 1
+		case close:
+			depth -= 1
+		}
+		p.pos += 1
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// directives will skip any directives, such as @include(if: $x).
+func (p *parser) directives() error {
+	for p.peek() == '@' {
+		p.pos += 1
+		if _, err := p.expect('n'); err != nil {
+			return err
+		}
+		if p.peek() == '(' {
+			if err := p.skip('(', ')'); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// selectionSet parses the selections between braces.
+func (p *parser) selectionSet() ([]selection, error) {
+	if _, err := p.expect('{'); err != nil {
+		return nil, err
+	}
+	sels := []selection{}
+	for p.peek() != '}' {
+		if p.peek() == 0 {
+			return nil, fmt.Errorf("%w: expected '}'", ErrSyntax)
+		}
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+	}
+	p.pos += 1
+	return sels, nil
+}
+
+// selection parses a field, fragment spread, or inline fragment.
+func (p *parser) selection() (selection, error) {
+	if p.peek() == '.' {
+		p.pos += 1
+		if p.peek() == 'n' && p.toks[p.pos].text != "on" {
+			name := p.toks[p.pos].text
+			p.pos += 1
+			return selection{spread: name}, p.directives()
+		}
+		if p.peek() == 'n' {
+			// Type condition, on Type.
+			p.pos += 1
+			if _, err := p.expect('n'); err != nil {
+				return selection{}, err
+			}
+		}
+		if err := p.directives(); err != nil {
+			return selection{}, err
+		}
+		sels, err := p.selectionSet()
+		return selection{inline: true, sels: sels}, err
+	}
+
+	name, err := p.expect('n')
+	if err != nil {
+		return selection{}, err
+	}
+	if p.peek() == ':' {
+		// Aliased, the field name follows.
+		p.pos += 1
+		if name, err = p.expect('n'); err != nil {
+			return selection{}, err
+		}
+	}
+	sel := selection{name: name.text}
+	if p.peek() == '(' {
+		if sel.size, err = p.arguments(); err != nil {
+			return selection{}, err
+		}
+	}
+	if err := p.directives(); err != nil {
+		return selection{}, err
+	}
+	if p.peek() == '{' {
+		if sel.sels, err = p.selectionSet(); err != nil {
+			return selection{}, err
+		}
+	}
+	return sel, nil
+}
+
+// arguments parses the arguments of a field, returning the value of the
+// first or last argument, 0 if neither is passed.
+func (p *parser) arguments() (int32, error) {
+	p.pos += 1
+	var size int32
+	for p.peek() != ')' {
+		arg, err := p.expect('n')
+		if err != nil {
+			return 0, err
+		}
+		if _, err := p.expect(':'); err != nil {
+			return 0, err
+		}
+		n, err := p.value()
+		if err != nil {
+			return 0, err
+		}
+		if arg.text == "first" || arg.text == "last" {
+			size = max(size, n)
+		}
+	}
+	p.pos += 1
+	return size, nil
+}
+
+// value parses an argument value, returning it as an integer if it is one,
+// resolving variables, or 0 otherwise.
+func (p *parser) value() (int32, error) {
+	switch p.peek() {
+	case '$':
+		p.pos += 1
+		name, err := p.expect('n')
+		if err != nil {
+			return 0, err
+		}
+		return toInt(p.vars[name.text]), nil
+	case '{':
+		return 0, p.skip('{', '}')
+	case '[':
+		return 0, p.skip('[', ']')
+	case 'v':
+		p.pos += 1
+		// Out of range literals parse as the nearest int32 bound.
+		n, _ := strconv.ParseInt(p.toks[p.pos-1].text, 10, 32)
+		return int32(n), nil
+	case 'n':
+		// Enum, boolean, or null.
+		p.pos += 1
+		return 0, nil
+	}
+	return 0, fmt.Errorf("%w: expected value at token %d", ErrSyntax, p.pos)
+}
+
+// toInt returns the variable value (v) as an integer, clamped to int32, or
+// 0 if it is not one.
+func toInt(v any) int32 {
+	var i int64
+	switch n := v.(type) {
+	case int:
+		i = int64(n)
+	case int32:
+		i = int64(n)
+	case int64:
+		i = n
+	case float64:
+		i = int64(min(max(n, math.MinInt32), math.MaxInt32))
+	case json.Number:
+		i, _ = n.Int64()
+	}
+	return int32(min(max(i, math.MinInt32), math.MaxInt32))
+}
+
+// cost returns the cost of the selections (sels). A fragment spread within
+// its own fragment is cyclic, and not counted.
+func (p *parser) cost(sels []selection) int64 {
+	var c, inline int64
+	for _, sel := range sels {
+		switch {
+		case sel.spread != "":
+			if _, ok := p.spreading[sel.spread]; ok {
+				continue
+			}
+			p.spreading[sel.spread] = struct{}{}
+			c = sat(c + p.cost(p.frags[sel.spread]))
+			delete(p.spreading, sel.spread)
+		case sel.inline:
+			inline = max(inline, p.cost(sel.sels))
+		case sel.sels == nil:
+			// Scalar or enum.
+		case sel.size > 0:
+			c = sat(c + 2 + int64(sel.size)*p.nodeCost(sel.sels))
+		case sel.name == "pageInfo":
+			// Included in the cost of the connection.
+		default:
+			c = sat(c + 1 + p.cost(sel.sels))
+		}
+	}
+	return sat(c + inline)
+}
+
+// nodeCost returns the cost of each node of a connection's selections
+// (sels), through its edges or nodes.
+func (p *parser) nodeCost(sels []selection) int64 {
+	var c int64
+	for _, sel := range sels {
+		switch sel.name {
+		case "edges":
+			for _, e := range sel.sels {
+				if e.name == "node" {
+					c = sat(c + p.cost(e.sels) + 1)
+				}
+			}
+		case "nodes":
+			c = sat(c + p.cost(sel.sels) + 1)
+		}
+	}
+	return c
+}
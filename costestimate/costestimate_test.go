@@ -0,0 +1,53 @@
+package costestimate
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// TestEstimate should follow the static cost heuristic.
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name  string         // Name of the case.
+		query string         // Query to estimate.
+		vars  map[string]any // Variables of the query.
+		excst int32          // Expected cost.
+	}{
+		{"scalars", `{ shop { name email } }`, nil, 1},
+		{"connection", `query { products(first: 10) { edges { node { id title } } pageInfo { hasNextPage } } }`, nil, 12},
+		{"nested", `query Products($first: Int!) {
+			products(first: $first) {
+				nodes {
+					id
+					variants(last: 5) { edges { node { id price } } }
+				}
+			}
+		}`, map[string]any{"first": 10}, 82},
+		{"fragments", `query { shop { ...ShopFields } }
+			fragment ShopFields on Shop { name primaryDomain { url } }`, nil, 2},
+		{"inline", `query { node(id: "gid://shopify/Product/1") { id ... on Product { title featuredImage { url } } ... on Collection { title } } }`, nil, 2},
+		{"aliases", `{ a: shop { name } b: shop { name } }`, nil, 2},
+		{"mutation", `mutation { productCreate(input: {title: "A"}) { product { id } userErrors { field message } } }`, nil, 10},
+		{"cyclic", `{ shop { ...A } } fragment A on Shop { parent { ...A } other { ...A } }`, nil, 3},
+		{"saturated", `{ a(first: 250) { nodes { b(first: 250) { nodes { c(first: 250) { nodes { d(first: 250) { nodes { id } } } } } } } } }`, nil, math.MaxInt32},
+		{"large literal", `{ a(first: 99999999999) { nodes { id } } }`, nil, math.MaxInt32},
+		{"large variable", `query($n: Int) { a(first: $n) { nodes { id } } }`, map[string]any{"n": int64(1) << 40}, math.MaxInt32},
+		{"comments", "{\n  # Only the shop.\n  shop { name }\n}", nil, 1},
+	}
+	for _, tt := range tests {
+		cst, err := Estimate(tt.query, tt.vars)
+		if err != nil || cst != tt.excst {
+			t.Errorf("%s: Estimate() = %d, %v; want %d, nil", tt.name, cst, err, tt.excst)
+		}
+	}
+}
+
+// TestEstimateSyntax should error for queries which can not be parsed.
+func TestEstimateSyntax(t *testing.T) {
+	for _, q := range []string{"", "{ shop { name }", `{ shop(id: "1) { name } }`, "query ( { }"} {
+		if _, err := Estimate(q, nil); !errors.Is(err, ErrSyntax) {
+			t.Errorf("Estimate(%q) = %v; want %v", q, err, ErrSyntax)
+		}
+	}
+}
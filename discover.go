@@ -0,0 +1,43 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// discoverQuery is the query made by DiscoverLimits, as cheap as possible
+// while still reporting the cost extension.
+const discoverQuery = `{ shop { id } }`
+
+// DiscoverLimits will make a minimal query to the GraphQL Admin API endpoint,
+// such as https://example.myshopify.com/admin/api/2024-01/graphql.json, with
+// the access token (token), through the Doer (d), or http.DefaultClient if
+// nil, purely to read the throttleStatus of the shop. It returns a pointer to
+// Balance configured with the shop's bucket size and restore rate, starting
+// at the points currently available, with a threshold of DefaultThresholdPct
+// of the bucket size and a QueryMax of GraphQLQueryMax, which the options
+// (opts) can override. This onboards a new shop with the limits of its plan
+// rather than assuming one.
+func DiscoverLimits(ctx context.Context, d Doer, endpoint string, token string, opts ...func(*Balance)) (*Balance, error) {
+	resp, err := postGraphQL(ctx, d, endpoint, token, discoverQuery, nil)
+	if err != nil {
+		return nil, fmt.Errorf("shopifysemaphore: discover limits: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("shopifysemaphore: discover limits: %w", err)
+	}
+
+	cost, err := ParseGraphQLCost(body)
+	if err == nil && cost.Remaining() == ErrPts {
+		err = ErrNoCost
+	}
+	if err != nil {
+		return nil, fmt.Errorf("shopifysemaphore: discover limits: %w", err)
+	}
+	ts := cost.ThrottleStatus
+	opts = append([]func(*Balance){WithInitialRemaining(cost.Remaining())}, graphQLOpts(opts)...)
+	return NewBalancePct(DefaultThresholdPct, int32(ts.MaximumAvailable), ts.RestoreRate, opts...), nil
+}
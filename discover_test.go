@@ -0,0 +1,39 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestDiscoverLimits should return a Balance configured from the
+// throttleStatus of the shop.
+func TestDiscoverLimits(t *testing.T) {
+	ctx := context.Background()
+	endpoint := "https://example.myshopify.com/admin/api/2024-01/graphql.json"
+	body := `{"data":{"shop":{"id":"gid://shopify/Shop/1"}},"extensions":{"cost":{"requestedQueryCost":1,"actualQueryCost":1,"throttleStatus":{"maximumAvailable":20000.0,"currentlyAvailable":19999,"restoreRate":1000.0}}}}`
+	d := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body))}, nil
+	})}
+
+	b, err := DiscoverLimits(ctx, d, endpoint, "token")
+	if err != nil {
+		t.Fatalf("DiscoverLimits() = %v; want nil", err)
+	}
+	if b.Limit != 20000 || b.RefillRate != 1000 || b.Threshold != 2000 || b.Remaining.Load() != 19999 || b.QueryMax != GraphQLQueryMax {
+		t.Errorf("DiscoverLimits() = %d, %v, %d, %d, %d; want 20000, 1000, 2000, 19999, %d", b.Limit, b.RefillRate, b.Threshold, b.Remaining.Load(), b.QueryMax, GraphQLQueryMax)
+	}
+
+	// Options override the defaults.
+	if b, _ := DiscoverLimits(ctx, d, endpoint, "token", WithQueryMax(500)); b.QueryMax != 500 {
+		t.Errorf("DiscoverLimits().QueryMax = %d; want 500", b.QueryMax)
+	}
+
+	body = `{"data":{"shop":{"id":"gid://shopify/Shop/1"}}}`
+	if _, err := DiscoverLimits(ctx, d, endpoint, "token"); !errors.Is(err, ErrNoCost) {
+		t.Errorf("DiscoverLimits() = %v; want %v", err, ErrNoCost)
+	}
+}
@@ -0,0 +1,111 @@
+package shopifysemaphore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// CodeThrottled is the extensions.code of a GraphQL error when the query
+// was throttled. GraphQL throttles arrive in a 200 OK response rather than
+// a 429 Too Many Requests.
+const CodeThrottled = "THROTTLED"
+
+// GraphQLError represents an error of a GraphQL Admin API response.
+type GraphQLError struct {
+	Message    string `json:"message"` // Description of the error.
+	Extensions struct {
+		Code string `json:"code"` // Code of the error, such as CodeThrottled.
+	} `json:"extensions"`
+}
+
+// GraphQLResponse represents the parts of a GraphQL Admin API response
+// regarding its cost, leaving the data to the caller.
+type GraphQLResponse struct {
+	Errors     []GraphQLError `json:"errors"` // Errors of the query, if any.
+	Extensions struct {
+		Cost *Cost `json:"cost"` // Cost of the query, nil if not reported.
+	} `json:"extensions"`
+}
+
+// ParseGraphQLResponse decodes the errors and cost extension of a GraphQL
+// Admin API response body.
+func ParseGraphQLResponse(body []byte) (GraphQLResponse, error) {
+	var resp GraphQLResponse
+	err := json.Unmarshal(body, &resp)
+	return resp, err
+}
+
+// postGraphQL will post the query, with its variables (vars), to the GraphQL
+// Admin API endpoint with the access token (token), through the Doer (d), or
+// http.DefaultClient if nil. It returns the response, erroring if it is not
+// 200 OK, in which case the body is already closed.
+func postGraphQL(ctx context.Context, d Doer, endpoint string, token string, query string, vars map[string]any) (*http.Response, error) {
+	if d == nil {
+		d = http.DefaultClient
+	}
+	body, err := json.Marshal(map[string]any{"query": query, "variables": vars})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Shopify-Access-Token", token)
+
+	resp, err := d.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// IsThrottled returns true if the response (resp) has an error with the
+// CodeThrottled code.
+func IsThrottled(resp GraphQLResponse) bool {
+	for _, e := range resp.Errors {
+		if e.Extensions.Code == CodeThrottled {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleGraphQLErrors will pause the Semaphore, with the PauseThrottled
+// reason, if the response (resp) was throttled. The pause lasts for as long
+// as the restore rate takes to refill the points the query requested, as
+// reported by the cost extension, or the refill duration of the model if
+// not reported, and is held for it even if the point balance recovers. It
+// will return false if the response was not throttled, in which case this
+// is a no-op.
+func (sem *Semaphore) HandleGraphQLErrors(resp GraphQLResponse) bool {
+	if !IsThrottled(resp) {
+		return false
+	}
+
+	defer sem.mu.Unlock()
+	sem.mu.Lock()
+	dur := sem.model.RefillDuration()
+	pts := ErrPts
+	if cost := resp.Extensions.Cost; cost != nil && cost.Remaining() > ErrPts {
+		pts = cost.Remaining()
+		sem.model.Update(pts)
+		if ts := cost.ThrottleStatus; ts.RestoreRate > 0 {
+			needed := max(cost.RequestedQueryCost-ts.CurrentlyAvailable, 0)
+			dur = time.Duration(needed / ts.RestoreRate * float64(time.Second))
+		}
+	}
+	sem.hold(pts, dur+sem.PauseBuffer, PauseThrottled)
+	return true
+}
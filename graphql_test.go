@@ -0,0 +1,74 @@
+package shopifysemaphore
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// throttledBody is a GraphQL response body throttled with a 200 OK.
+const throttledBody = `{
+	"errors": [{"message": "Throttled", "extensions": {"code": "THROTTLED"}}],
+	"extensions": {
+		"cost": {
+			"requestedQueryCost": 500,
+			"actualQueryCost": null,
+			"throttleStatus": {"maximumAvailable": 1000.0, "currentlyAvailable": 400.0, "restoreRate": 50.0}
+		}
+	}
+}`
+
+// TestIsThrottled should recognize the THROTTLED error code.
+func TestIsThrottled(t *testing.T) {
+	tests := []struct {
+		body string // Response body.
+		exok bool   // Expected to be throttled.
+	}{
+		{throttledBody, true},
+		{`{"errors":[{"message":"Field 'x' doesn't exist","extensions":{"code":"undefinedField"}}]}`, false},
+		{`{"data":{}}`, false},
+	}
+	for _, tt := range tests {
+		resp, err := ParseGraphQLResponse([]byte(tt.body))
+		if err != nil {
+			t.Fatalf("ParseGraphQLResponse(%q) = %v; want nil", tt.body, err)
+		}
+		if ok := IsThrottled(resp); ok != tt.exok {
+			t.Errorf("IsThrottled(%q) = %v; want %v", tt.body, ok, tt.exok)
+		}
+	}
+}
+
+// TestHandleGraphQLErrors should pause for the restore rate to refill the
+// points requested.
+func TestHandleGraphQLErrors(t *testing.T) {
+	paused := make(chan PauseInfo, 1)
+	sema := newSemaphore(1, WithPauseInfoFunc(func(info PauseInfo) { paused <- info }))
+	if sema.HandleGraphQLErrors(GraphQLResponse{}) {
+		t.Error("HandleGraphQLErrors() = true; want false")
+	}
+
+	resp, _ := ParseGraphQLResponse([]byte(throttledBody))
+	if !sema.HandleGraphQLErrors(resp) {
+		t.Fatal("HandleGraphQLErrors() = false; want true")
+	}
+	// 100 points short, at 50 per second.
+	if info := <-paused; info.Duration != 2*time.Second || info.Reason != PauseThrottled || info.Remaining != 400 {
+		t.Errorf("PauseInfoFunc(%+v); want 2s, %v, and 400 remaining", info, PauseThrottled)
+	}
+}
+
+// TestTransportThrottled should release a throttled GraphQL response with
+// ErrThrottled and pause.
+func TestTransportThrottled(t *testing.T) {
+	sema := newSemaphore(1)
+	client := &http.Client{Transport: NewTransport(sema, respond(200, "", "", throttledBody))}
+	resp, err := client.Post("https://example.myshopify.com/admin/api/2024-01/graphql.json", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Post() = %v; want nil", err)
+	}
+	resp.Body.Close()
+	if st := sema.Stats(); st.InFlight != 0 || !st.Paused || st.LastErr != ErrThrottled || st.Remaining != 400 {
+		t.Errorf("Stats() = %+v; want released with %v, paused, and 400 remaining", st, ErrThrottled)
+	}
+}
@@ -0,0 +1,91 @@
+package shopifysemaphore
+
+import (
+	"sync"
+	"time"
+)
+
+// PauseGroup represents a set of Semaphores which can be paused together,
+// such as every per-shop Semaphore of a platform, acting as one switch to
+// stop all outbound API pressure during an incident. It is safe for
+// concurrent use.
+type PauseGroup struct {
+	mu      sync.Mutex              // For handling the members.
+	members map[*Semaphore]struct{} // Semaphores in the group.
+}
+
+// NewPauseGroup returns a pointer to an empty PauseGroup.
+func NewPauseGroup() *PauseGroup {
+	return &PauseGroup{members: make(map[*Semaphore]struct{})}
+}
+
+// Join will add the Semaphore to the group.
+func (g *PauseGroup) Join(sem *Semaphore) {
+	defer g.mu.Unlock()
+	g.mu.Lock()
+	g.members[sem] = struct{}{}
+}
+
+// Leave will remove the Semaphore from the group, such as when the shop
+// uninstalls the app. Any pause already in progress is not affected.
+func (g *PauseGroup) Leave(sem *Semaphore) {
+	defer g.mu.Unlock()
+	g.mu.Lock()
+	delete(g.members, sem)
+}
+
+// Len returns the number of Semaphores in the group.
+func (g *PauseGroup) Len() int {
+	defer g.mu.Unlock()
+	g.mu.Lock()
+	return len(g.members)
+}
+
+// PauseAll will pause every Semaphore in the group for the duration (dur)
+// with the PauseManual reason. The pause is held for the duration, even if
+// the point balance recovers, and a Semaphore which is already paused is
+// held until at least the duration has passed.
+func (g *PauseGroup) PauseAll(dur time.Duration) {
+	for _, sem := range g.list() {
+		sem.mu.Lock()
+		sem.hold(sem.remaining(), dur, PauseManual)
+		sem.mu.Unlock()
+	}
+}
+
+// ResumeAll will resume every paused Semaphore in the group right away,
+// releasing any hold on the pause, including closing an open circuit
+// breaker, such as once an incident is over.
+func (g *PauseGroup) ResumeAll() {
+	for _, sem := range g.list() {
+		sem.mu.Lock()
+		sem.holdUntil = time.Time{}
+		if sem.breaker != nil {
+			sem.breaker.until = time.Time{}
+		}
+		if sem.paused {
+			sem.resumeEarly()
+		}
+		sem.mu.Unlock()
+	}
+}
+
+// list returns the Semaphores in the group, so they can be paused or resumed
+// without holding the group's lock.
+func (g *PauseGroup) list() []*Semaphore {
+	defer g.mu.Unlock()
+	g.mu.Lock()
+	sems := make([]*Semaphore, 0, len(g.members))
+	for sem := range g.members {
+		sems = append(sems, sem)
+	}
+	return sems
+}
+
+// WithPauseGroup is a functional option for Semaphore which will join it
+// to the PauseGroup (g).
+func WithPauseGroup(g *PauseGroup) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		g.Join(sem)
+	}
+}
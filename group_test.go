@@ -0,0 +1,50 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestPauseGroup should pause every member, holding the pause even once
+// the point balance recovers, until resumed.
+func TestPauseGroup(t *testing.T) {
+	g := NewPauseGroup()
+	a := newSemaphore(1, WithPauseGroup(g), WithEarlyResume(0.5))
+	b := newSemaphore(1)
+	g.Join(b)
+	if n := g.Len(); n != 2 {
+		t.Errorf("PauseGroup.Len() = %d; want 2", n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := a.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+
+	g.PauseAll(time.Minute)
+	for _, sema := range []*Semaphore{a, b} {
+		if st := sema.Stats(); !st.Paused {
+			t.Errorf("Stats().Paused = false; want true")
+		}
+	}
+
+	// Recovered, but held by the group.
+	a.Release(1000)
+	if st := a.Stats(); !st.Paused {
+		t.Errorf("Stats().Paused = false; want true while held")
+	}
+
+	g.ResumeAll()
+	for _, sema := range []*Semaphore{a, b} {
+		if err := sema.Acquire(ctx); err != nil {
+			t.Errorf("Acquire(%q) = %v; want nil", ctx, err)
+		}
+	}
+
+	g.Leave(b)
+	if n := g.Len(); n != 1 {
+		t.Errorf("PauseGroup.Len() = %d; want 1", n)
+	}
+}
@@ -0,0 +1,72 @@
+package shopifysemaphore
+
+import (
+	"slices"
+	"sort"
+)
+
+// DefaultCostBounds are the upper bounds, inclusive, of the cost histogram
+// buckets used unless set with WithCostHistogram.
+var DefaultCostBounds = []int32{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000}
+
+// Histogram represents the distribution of point costs consumed between
+// updates of a Balance, such as to see whether a workload is dominated by
+// cheap or expensive queries.
+type Histogram struct {
+	Bounds []int32 // Upper bounds, inclusive, of each bucket, sorted.
+	Counts []int   // Number of costs in each bucket, with a final bucket for costs above every bound.
+	Total  int64   // Sum of every cost recorded.
+}
+
+// histogram records costs into buckets.
+type histogram struct {
+	bounds []int32 // Upper bounds, inclusive, of each bucket, sorted.
+	counts []int   // Number of costs in each bucket, plus one for the overflow.
+	total  int64   // Sum of every cost recorded.
+}
+
+// newHistogram returns a histogram with the upper bounds, inclusive, of
+// each bucket.
+func newHistogram(bounds []int32) histogram {
+	bs := slices.Clone(bounds)
+	slices.Sort(bs)
+	bs = slices.Compact(bs)
+	return histogram{bounds: bs, counts: make([]int, len(bs)+1)}
+}
+
+// record will count the cost into its bucket, using DefaultCostBounds
+// if no buckets were set.
+func (h *histogram) record(cost int32) {
+	if h.counts == nil {
+		*h = newHistogram(DefaultCostBounds)
+	}
+	i := sort.Search(len(h.bounds), func(i int) bool {
+		return cost <= h.bounds[i]
+	})
+	h.counts[i] += 1
+	h.total += int64(cost)
+}
+
+// CostHistogram returns a snapshot of the distribution of points consumed
+// between successive calls to Update, accounting for the points refilled
+// in between, in the buckets set by WithCostHistogram.
+func (b *Balance) CostHistogram() Histogram {
+	defer b.rmu.Unlock()
+	b.rmu.Lock()
+	if b.costs.counts == nil {
+		b.costs = newHistogram(DefaultCostBounds)
+	}
+	return Histogram{
+		Bounds: slices.Clone(b.costs.bounds),
+		Counts: slices.Clone(b.costs.counts),
+		Total:  b.costs.total,
+	}
+}
+
+// WithCostHistogram is a functional option for Balance which will set the
+// upper bounds, inclusive, of the buckets for CostHistogram.
+func WithCostHistogram(bounds ...int32) func(*Balance) {
+	return func(b *Balance) {
+		b.costs = newHistogram(bounds)
+	}
+}
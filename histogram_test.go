@@ -0,0 +1,42 @@
+package shopifysemaphore
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestHistogram should count costs into their buckets, with an
+// overflow bucket for costs above every bound.
+func TestHistogram(t *testing.T) {
+	h := newHistogram([]int32{100, 10, 10, 50})
+	for _, cost := range []int32{0, 10, 11, 50, 99, 500} {
+		h.record(cost)
+	}
+	if !slices.Equal(h.bounds, []int32{10, 50, 100}) {
+		t.Errorf("bounds = %v; want [10 50 100]", h.bounds)
+	}
+	if !slices.Equal(h.counts, []int{2, 2, 1, 1}) {
+		t.Errorf("counts = %v; want [2 2 1 1]", h.counts)
+	}
+	if h.total != 670 {
+		t.Errorf("total = %d; want 670", h.total)
+	}
+}
+
+// TestCostHistogram should record the points consumed between updates.
+func TestCostHistogram(t *testing.T) {
+	b := NewBalance(0, 1000, 0, WithCostHistogram(10, 100))
+	b.Update(995)
+	b.Update(945)
+	b.Update(545)
+
+	h := b.CostHistogram()
+	if !slices.Equal(h.Counts, []int{1, 1, 1}) || h.Total != 455 {
+		t.Errorf("Balance.CostHistogram() = %+v; want counts [1 1 1] and total 455", h)
+	}
+
+	// Default buckets.
+	if h := newBalance().CostHistogram(); !slices.Equal(h.Bounds, DefaultCostBounds) {
+		t.Errorf("Balance.CostHistogram().Bounds = %v; want %v", h.Bounds, DefaultCostBounds)
+	}
+}
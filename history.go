@@ -0,0 +1,58 @@
+package shopifysemaphore
+
+import "time"
+
+// UpdateSource represents what changed the remaining points of a Balance.
+type UpdateSource string
+
+const (
+	SourceUpdate  UpdateSource = "update"  // Changed by Update.
+	SourceSync    UpdateSource = "sync"    // Changed by Sync.
+	SourceConsume UpdateSource = "consume" // Changed by Consume or TryConsume.
+	SourceReserve UpdateSource = "reserve" // Changed by Reserve.
+	SourceRefund  UpdateSource = "refund"  // Changed by Refund.
+	SourceRestore UpdateSource = "restore" // Changed by Restore.
+)
+
+// UpdateRecord represents a single change to the remaining points of a Balance.
+type UpdateRecord struct {
+	At     time.Time    // When the change happened.
+	Old    int32        // Remaining points before the change.
+	New    int32        // Remaining points after the change.
+	Source UpdateSource // What made the change.
+}
+
+// History returns the last changes to the remaining points, oldest first,
+// up to the number set by WithHistory, so the exact sequence of point
+// reports leading to a misbehavior can be seen. It returns nil if
+// WithHistory was not used.
+func (b *Balance) History() []UpdateRecord {
+	defer b.wmu.Unlock()
+	b.wmu.Lock()
+	n := cap(b.history)
+	if n == 0 {
+		return nil
+	}
+	if len(b.history) < n {
+		return append([]UpdateRecord(nil), b.history...)
+	}
+
+	// Full, the oldest is next to be overwritten.
+	i := b.histN % n
+	return append(append([]UpdateRecord(nil), b.history[i:]...), b.history[:i]...)
+}
+
+// record will append the change (rec) into the history, overwriting the
+// oldest once full. It must be called while holding wmu.
+func (b *Balance) record(rec UpdateRecord) {
+	n := cap(b.history)
+	if n == 0 {
+		return
+	}
+	if len(b.history) < n {
+		b.history = append(b.history, rec)
+	} else {
+		b.history[b.histN%n] = rec
+	}
+	b.histN += 1
+}
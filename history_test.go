@@ -0,0 +1,32 @@
+package shopifysemaphore
+
+import "testing"
+
+// TestHistory should keep the last changes to the remaining points,
+// oldest first.
+func TestHistory(t *testing.T) {
+	if h := newBalance().History(); h != nil {
+		t.Errorf("Balance.History() = %v; want nil", h)
+	}
+
+	b := NewBalance(100, 1000, 0, WithHistory(3))
+	b.Update(900)
+	b.Consume(100)
+	b.Refund(50)
+	b.Sync(500, 0, 0)
+
+	exh := []UpdateRecord{
+		{Old: 900, New: 800, Source: SourceConsume},
+		{Old: 800, New: 850, Source: SourceRefund},
+		{Old: 850, New: 500, Source: SourceSync},
+	}
+	h := b.History()
+	if len(h) != len(exh) {
+		t.Fatalf("Balance.History() = %v; want %v", h, exh)
+	}
+	for i, rec := range h {
+		if rec.Old != exh[i].Old || rec.New != exh[i].New || rec.Source != exh[i].Source || rec.At.IsZero() {
+			t.Errorf("Balance.History()[%d] = %+v; want %+v", i, rec, exh[i])
+		}
+	}
+}
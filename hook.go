@@ -0,0 +1,48 @@
+package shopifysemaphore
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrHookPanic is matched, through errors.Is, by the HookPanicError passed
+// to the HookErrorHandler when a callback panics.
+var ErrHookPanic = errors.New("shopifysemaphore: hook panicked")
+
+// HookPanicError is passed to the HookErrorHandler when a callback, such as
+// the PauseFunc or OnRelease, panics. The panic is contained so it does not
+// crash the process from a Goroutine the caller does not own.
+type HookPanicError struct {
+	Hook  string // Name of the callback which panicked, such as "PauseFunc".
+	Value any    // Value the callback panicked with.
+}
+
+// Error returns the error message, including the callback and panic value.
+func (e *HookPanicError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", ErrHookPanic, e.Hook, e.Value)
+}
+
+// Unwrap returns ErrHookPanic so the error can be matched with errors.Is.
+func (e *HookPanicError) Unwrap() error {
+	return ErrHookPanic
+}
+
+// hook will run the callback (fn) named hook, recovering from any panic and
+// passing it to the HookErrorHandler as a HookPanicError.
+func (sem *Semaphore) hook(hook string, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			sem.HookErrorHandler(&HookPanicError{Hook: hook, Value: r})
+		}
+	}()
+	fn()
+}
+
+// WithHookErrorHandler is a functional option for Semaphore to call when a
+// callback, such as the PauseFunc or OnRelease, panics. The panic is always
+// recovered from, by default it is discarded.
+func WithHookErrorHandler(fn func(error)) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.HookErrorHandler = fn
+	}
+}
@@ -0,0 +1,52 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestHookErrorHandler should recover from a panicking callback and
+// pass it to the handler.
+func TestHookErrorHandler(t *testing.T) {
+	errs := make(chan error, 2)
+	ctx := context.Background()
+	sema := newSemaphore(1, WithHookErrorHandler(func(err error) {
+		errs <- err
+	}), WithPauseFunc(func(_ int32, _ time.Duration) {
+		panic("pause")
+	}), WithOnRelease(func(_ ReleaseInfo) {
+		panic("release")
+	}), WithMaxPause(time.Millisecond))
+
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(0)
+
+	hooks := make(map[string]any)
+	for i := 0; i < 2; i += 1 {
+		select {
+		case err := <-errs:
+			var hpe *HookPanicError
+			if !errors.As(err, &hpe) || !errors.Is(err, ErrHookPanic) {
+				t.Fatalf("HookErrorHandler(%v); want %v", err, ErrHookPanic)
+			}
+			hooks[hpe.Hook] = hpe.Value
+		case <-time.After(time.Second):
+			t.Fatalf("HookErrorHandler not called; want called twice")
+		}
+	}
+	if v := hooks["PauseFunc"]; v != "pause" {
+		t.Errorf("HookPanicError{Hook: PauseFunc}.Value = %v; want pause", v)
+	}
+	if v := hooks["OnRelease"]; v != "release" {
+		t.Errorf("HookPanicError{Hook: OnRelease}.Value = %v; want release", v)
+	}
+
+	// Still usable after the panics.
+	if err := sema.Acquire(ctx); err != nil {
+		t.Errorf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+}
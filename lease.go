@@ -0,0 +1,158 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrLeaseExpired is recorded against the Semaphore's stats as a failed
+// request when a Lease is automatically released.
+var ErrLeaseExpired = errors.New("shopifysemaphore: lease expired")
+
+// Lease represents a spot acquired through AcquireLease. If the Semaphore
+// has a LeaseTTL set and the Lease is not released within that duration,
+// such as when a Goroutine crashes or hangs, the spot is automatically
+// released so capacity is not leaked forever. The spot must be released
+// through the Lease, releasing it through Semaphore.Release will panic if
+// no other spot is held, the same as any release of an unheld spot.
+type Lease struct {
+	sem        *Semaphore  // Semaphore the spot was acquired from.
+	class      string      // Partition class the spot is counted against, if any.
+	acquiredAt time.Time   // When the spot was acquired.
+	timer      *time.Timer // Timer for automatic release, nil if no TTL.
+	released   atomic.Bool // If the spot has been released, manually or automatically.
+	expired    atomic.Bool // If the spot was automatically released.
+}
+
+// AcquireLease will attempt to acquire a spot to run the Goroutine in the
+// same way as Acquire, returning a Lease which must be used to release the
+// spot. If LeaseTTL is set, the spot will be automatically released after
+// that duration and LeaseExpiredFunc will be called.
+func (sem *Semaphore) AcquireLease(ctx context.Context) (*Lease, error) {
+	return sem.lease(ctx, "")
+}
+
+// lease handles acquiring a spot for AcquireLease and AcquireClass. If class
+// is set, the spot is counted against that partition class.
+func (sem *Semaphore) lease(ctx context.Context, class string) (*Lease, error) {
+	if _, err := sem.acquire(ctx, PriorityNormal, class, class != ""); err != nil {
+		return nil, err
+	}
+	sem.mu.Lock()
+	sem.leased += 1
+	sem.mu.Unlock()
+
+	l := &Lease{
+		sem:        sem,
+		class:      class,
+		acquiredAt: time.Now(),
+	}
+	if sem.LeaseTTL > 0 {
+		l.timer = time.AfterFunc(sem.LeaseTTL, l.expire)
+	}
+	return l, nil
+}
+
+// Release will release the spot held by the Lease, accepting a current
+// value of remaining point balance in the same way as Semaphore.Release.
+// It will return false if the Lease was already released, such as when
+// it has expired, in which case this is a no-op.
+func (l *Lease) Release(pts int32) bool {
+	if !l.stop() {
+		return false
+	}
+	l.sem.release(pts, nil, true)
+	return true
+}
+
+// ReleaseWithErr will release the spot held by the Lease in the same way
+// as Semaphore.ReleaseWithErr. It will return false if the Lease was
+// already released, such as when it has expired, in which case this is
+// a no-op.
+func (l *Lease) ReleaseWithErr(err error) bool {
+	if !l.stop() {
+		return false
+	}
+	l.sem.release(ErrPts, err, true)
+	return true
+}
+
+// stop will flag the Lease as released and stop the automatic release,
+// returning false if the Lease was already released.
+func (l *Lease) stop() bool {
+	if !l.released.CompareAndSwap(false, true) {
+		return false
+	}
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	l.unclass()
+	return true
+}
+
+// unclass will stop counting the spot against its partition class, if any,
+// ahead of the spot being released.
+func (l *Lease) unclass() {
+	if l.class == "" {
+		return
+	}
+	defer l.sem.mu.Unlock()
+	l.sem.mu.Lock()
+	l.sem.classes[l.class] -= 1
+}
+
+// AcquiredAt returns when the spot for the Lease was acquired.
+func (l *Lease) AcquiredAt() time.Time {
+	return l.acquiredAt
+}
+
+// Expired returns true if the Lease was automatically released
+// due to not being released within LeaseTTL.
+func (l *Lease) Expired() bool {
+	return l.expired.Load()
+}
+
+// expire will automatically release the spot held by the Lease and run
+// the LeaseExpiredFunc. The point balance is stale at this point, so it is
+// not updated and no pause is considered, the expiry is only recorded as a
+// failed request of ErrLeaseExpired, the same as ReleaseWithErr would.
+func (l *Lease) expire() {
+	if !l.released.CompareAndSwap(false, true) {
+		return
+	}
+	l.expired.Store(true)
+
+	l.unclass()
+	l.sem.releaseExpired(true)
+	l.sem.mu.Lock()
+	fn := l.sem.LeaseExpiredFunc
+	l.sem.mu.Unlock()
+	l.sem.hook("LeaseExpiredFunc", func() { fn(l) })
+}
+
+// releaseExpired will release a spot which has expired, recording it as
+// a failed request of ErrLeaseExpired and running the OnRelease hook, but
+// without updating the point balance or considering a pause. For a child,
+// the spot held on the parent is also released. Leased should be true
+// if the spot is held by a Lease.
+func (sem *Semaphore) releaseExpired(leased bool) {
+	func() {
+		defer sem.mu.Unlock()
+		sem.mu.Lock()
+		sem.checkHeld(leased)
+		sem.record(ErrLeaseExpired)
+		sem.free(leased)
+	}()
+	if sem.parent != nil {
+		sem.parent.releaseExpired(false)
+		return
+	}
+
+	pts := sem.remaining()
+	sem.mu.Lock()
+	fn := sem.OnRelease
+	sem.mu.Unlock()
+	sem.hook("OnRelease", func() { fn(ReleaseInfo{Before: pts, After: pts, Err: ErrLeaseExpired}) })
+}
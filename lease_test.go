@@ -0,0 +1,101 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestLeaseRelease should release the spot once, with further
+// releases being a no-op.
+func TestLeaseRelease(t *testing.T) {
+	ctx := context.Background()
+	sema := newSemaphore(1)
+
+	l, err := sema.AcquireLease(ctx)
+	if err != nil {
+		t.Fatalf("AcquireLease(%q) = %v; want nil", ctx, err)
+	}
+	if ok := l.Release(1000); !ok {
+		t.Errorf("Lease.Release(1000) = %v; want true", ok)
+	}
+	if ok := l.Release(1000); ok {
+		t.Errorf("Lease.Release(1000) = %v; want false", ok)
+	}
+
+	// Spot should be available again, and only once.
+	if err := sema.Acquire(ctx); err != nil {
+		t.Errorf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	if sema.inflight != 1 {
+		t.Errorf("inflight = %d; want 1", sema.inflight)
+	}
+}
+
+// TestLeaseExpire should automatically release the spot after the
+// TTL and report the expired lease through the callback.
+func TestLeaseExpire(t *testing.T) {
+	expired := make(chan *Lease, 1)
+
+	ctx := context.Background()
+	sema := newSemaphore(1, WithLeaseTTL(10*time.Millisecond), WithLeaseExpiredFunc(func(l *Lease) {
+		expired <- l
+	}), WithPauseFunc(func(pts int32, _ time.Duration) {
+		t.Errorf("PauseFunc(%d, _) called; want no pause on expiry", pts)
+	}))
+	sema.Update(0) // Stale balance at the threshold.
+
+	l, err := sema.AcquireLease(ctx)
+	if err != nil {
+		t.Fatalf("AcquireLease(%q) = %v; want nil", ctx, err)
+	}
+
+	// Should be able to acquire the only spot once the lease expires.
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+
+	if el := <-expired; el != l {
+		t.Errorf("LeaseExpiredFunc(%p); want LeaseExpiredFunc(%p)", el, l)
+	}
+	if !l.Expired() {
+		t.Errorf("Lease.Expired() = false; want true")
+	}
+	if ok := l.Release(1000); ok {
+		t.Errorf("Lease.Release(1000) = %v; want false", ok)
+	}
+	if st := sema.Stats(); st.Failures != 1 || !errors.Is(st.LastErr, ErrLeaseExpired) {
+		t.Errorf("Stats() = %+v; want 1 failure of %v", st, ErrLeaseExpired)
+	}
+}
+
+// TestLeaseReleaseThroughSemaphore should panic when a spot held by
+// a Lease is released through the Semaphore, such as a hung Goroutine
+// waking after its lease expired.
+func TestLeaseReleaseThroughSemaphore(t *testing.T) {
+	ctx := context.Background()
+	sema := newSemaphore(1)
+
+	l, err := sema.AcquireLease(ctx)
+	if err != nil {
+		t.Fatalf("AcquireLease(%q) = %v; want nil", ctx, err)
+	}
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("Release(1000) did not panic; want panic")
+			}
+		}()
+		sema.Release(1000)
+	}()
+
+	if ok := l.ReleaseWithErr(errors.New("failed")); !ok {
+		t.Errorf("Lease.ReleaseWithErr(_) = %v; want true", ok)
+	}
+	if st := sema.Stats(); st.InFlight != 0 || st.Failures != 1 {
+		t.Errorf("Stats() = %+v; want 0 in flight and 1 failure", st)
+	}
+}
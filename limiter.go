@@ -0,0 +1,142 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"time"
+)
+
+// Limiter represents anything which can regulate the running of Goroutines,
+// such as Semaphore. It allows for rate limiting to be swapped out, feature
+// flagged off with NopLimiter, or mocked in tests.
+type Limiter interface {
+	Acquire(ctx context.Context) error // Acquire a spot to run.
+	Release(pts int32)                 // Release a spot, with the remaining point balance.
+	ReleaseWithErr(err error)          // Release a spot, for a failed request.
+	Stats() Stats                      // Snapshot of the limiter's state.
+}
+
+// Stats represents a snapshot of a Limiter's state at a point in time.
+type Stats struct {
+	InFlight     int           // Number of Goroutines holding a spot.
+	Waiters      int           // Number of Goroutines waiting for a spot.
+	Capacity     int           // Number of Goroutines which can currently run at a time, 0 or less for no cap.
+	Acquisitions int           // Total number of spots acquired.
+	Pauses       int           // Total number of pauses.
+	PausedFor    time.Duration // Cumulative duration spent paused, including any current pause.
+	Paused       bool          // If the limiter is currently paused.
+	CircuitOpen  bool          // If the circuit breaker is currently open.
+	Uptime       time.Duration // Duration since the limiter was created.
+	MarkPauses   int           // Number of pauses since the last Mark.
+	MarkPaused   time.Duration // Cumulative duration spent paused since the last Mark, including any current pause.
+	SinceMark    time.Duration // Duration since the last Mark, or since created if never marked.
+	Remaining    int32         // Point balance remaining.
+	Failures     int           // Number of releases which were for failed requests.
+	LastErr      error         // Error from the last failed request.
+}
+
+// PausedPct returns the fraction of the Uptime spent paused, such as 0.25
+// for a quarter of the time, for capacity planning.
+func (st Stats) PausedPct() float64 {
+	if st.Uptime <= 0 {
+		return 0
+	}
+	return float64(st.PausedFor) / float64(st.Uptime)
+}
+
+// MarkPausedPct returns the fraction of the time since the last Mark spent
+// paused, in the same way as PausedPct.
+func (st Stats) MarkPausedPct() float64 {
+	if st.SinceMark <= 0 {
+		return 0
+	}
+	return float64(st.MarkPaused) / float64(st.SinceMark)
+}
+
+// Stats returns a snapshot of the Semaphore's state, taken in one go so
+// the values are consistent with each other.
+func (sem *Semaphore) Stats() Stats {
+	defer sem.mu.Unlock()
+	sem.mu.Lock()
+
+	now := time.Now()
+	pausedFor := sem.totalPaused(now)
+	return Stats{
+		InFlight:     sem.inflight,
+		Waiters:      sem.queue.len(),
+		Capacity:     sem.capacity(),
+		Acquisitions: sem.acquisitions,
+		Pauses:       sem.pauses,
+		PausedFor:    pausedFor,
+		Paused:       sem.paused,
+		CircuitOpen:  sem.circuitOpen(),
+		Uptime:       now.Sub(sem.startedAt),
+		MarkPauses:   sem.pauses - sem.mark.pauses,
+		MarkPaused:   pausedFor - sem.mark.pausedFor,
+		SinceMark:    now.Sub(sem.mark.at),
+		Remaining:    sem.remaining(),
+		Failures:     sem.failures,
+		LastErr:      sem.lastErr,
+	}
+}
+
+// Mark will reset the pause counts and durations since the last Mark, as
+// reported by Stats, such as at the start of each reporting interval. The
+// totals since the Semaphore was created are not affected.
+func (sem *Semaphore) Mark() {
+	defer sem.mu.Unlock()
+	sem.mu.Lock()
+
+	now := time.Now()
+	sem.mark = statsMark{at: now, pauses: sem.pauses, pausedFor: sem.totalPaused(now)}
+}
+
+// statsMark holds the pause totals as of the last Mark.
+type statsMark struct {
+	at        time.Time     // When marked.
+	pauses    int           // Number of pauses when marked.
+	pausedFor time.Duration // Cumulative duration paused when marked.
+}
+
+// totalPaused returns the cumulative duration spent paused, including any
+// current pause, as of now. It must be called while holding mu.
+func (sem *Semaphore) totalPaused(now time.Time) time.Duration {
+	pausedFor := sem.pausedFor
+	if sem.paused {
+		pausedFor += now.Sub(sem.pausedAt)
+	}
+	return pausedFor
+}
+
+// NopLimiter is a Limiter which never blocks or pauses. It can be used
+// to switch off rate limiting, or in place of a Semaphore in tests.
+type NopLimiter struct{}
+
+// Acquire will always acquire immediately, unless the context is
+// already cancelled.
+func (NopLimiter) Acquire(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// Aquire will always acquire immediately, unless the context is
+// already cancelled.
+//
+// Deprecated: use Acquire.
+func (NopLimiter) Aquire(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// Release is a no-op.
+func (NopLimiter) Release(_ int32) {}
+
+// ReleaseWithErr is a no-op.
+func (NopLimiter) ReleaseWithErr(_ error) {}
+
+// Stats returns an empty snapshot.
+func (NopLimiter) Stats() Stats {
+	return Stats{}
+}
+
+var (
+	_ Limiter = (*Semaphore)(nil)
+	_ Limiter = NopLimiter{}
+)
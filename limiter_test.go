@@ -0,0 +1,112 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestSemaphoreStats should reflect the spots held and waiting.
+func TestSemaphoreStats(t *testing.T) {
+	ctx := context.Background()
+	sema := newSemaphore(1)
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- sema.Acquire(ctx)
+	}()
+	waitFor(sema, 1)
+
+	st := sema.Stats()
+	st.Uptime, st.SinceMark = 0, 0 // Depend on timing.
+	exst := Stats{InFlight: 1, Waiters: 1, Capacity: 1, Acquisitions: 1, Remaining: 1000}
+	if st != exst {
+		t.Errorf("Stats() = %+v; want %+v", st, exst)
+	}
+
+	sema.Release(1000)
+	<-done
+	sema.Release(1000)
+}
+
+// TestNopLimiter should never block and only fail on a cancelled context.
+func TestNopLimiter(t *testing.T) {
+	var l Limiter = NopLimiter{}
+
+	ctx := context.Background()
+	for i := 0; i < 3; i += 1 {
+		if err := l.Acquire(ctx); err != nil {
+			t.Errorf("NopLimiter.Acquire(%q) = %v; want nil", ctx, err)
+		}
+	}
+	l.Release(0)
+	l.ReleaseWithErr(errors.New("failed"))
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := l.Acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("NopLimiter.Acquire(%q) = %v; want %v", ctx, err, context.Canceled)
+	}
+	if st := l.Stats(); st != (Stats{}) {
+		t.Errorf("NopLimiter.Stats() = %+v; want empty", st)
+	}
+}
+
+// TestSemaphoreStatsPauses should count the pauses and the
+// duration spent paused.
+func TestSemaphoreStatsPauses(t *testing.T) {
+	ctx := context.Background()
+	sema := NewSemaphore(1, NewBalance(995, 1000, 100), WithPauseBuffer(20*time.Millisecond))
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(990) // Pause for 20ms.
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+
+	st := sema.Stats()
+	if st.Pauses != 1 || st.Acquisitions != 2 {
+		t.Errorf("Stats() = %+v; want 1 pause and 2 acquisitions", st)
+	}
+	if st.PausedFor < 20*time.Millisecond {
+		t.Errorf("Stats().PausedFor = %v; want at least 20ms", st.PausedFor)
+	}
+}
+
+// TestSemaphoreStatsMark should count the pauses and the duration spent
+// paused since the last Mark, separately from the totals.
+func TestSemaphoreStatsMark(t *testing.T) {
+	resumed := make(chan bool, 1)
+	sema := newSemaphore(1, WithResumeFunc(func() {
+		resumed <- true
+	}))
+	sema.PauseFor(20 * time.Millisecond)
+	<-resumed
+	sema.Mark()
+
+	st := sema.Stats()
+	if st.Pauses != 1 || st.MarkPauses != 0 || st.MarkPaused != 0 {
+		t.Errorf("Stats() = %+v; want 1 pause, none since the mark", st)
+	}
+	if pct := st.PausedPct(); pct <= 0 || pct > 1 {
+		t.Errorf("Stats().PausedPct() = %v; want between 0 and 1", pct)
+	}
+
+	sema.PauseFor(10 * time.Millisecond)
+	<-resumed
+	st = sema.Stats()
+	if st.Pauses != 2 || st.MarkPauses != 1 {
+		t.Errorf("Stats() = %+v; want 2 pauses, 1 since the mark", st)
+	}
+	if st.MarkPaused < 10*time.Millisecond || st.MarkPaused >= st.PausedFor {
+		t.Errorf("Stats().MarkPaused = %v; want at least 10ms and less than %v", st.MarkPaused, st.PausedFor)
+	}
+	if st.SinceMark >= st.Uptime {
+		t.Errorf("Stats().SinceMark = %v; want less than %v", st.SinceMark, st.Uptime)
+	}
+}
@@ -0,0 +1,41 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"time"
+)
+
+// BalanceModel represents a model of a point balance which a Semaphore
+// consumes to decide when to pause and for how long. Balance is the default
+// model, but alternatives, such as a leaky bucket, token bucket, or fixed
+// window, can be swapped in through NewSemaphoreModel.
+type BalanceModel interface {
+	Update(points int32)           // Store a new value of remaining points, ignoring ErrPts.
+	AtThreshold() bool             // If the remaining points are at or below where a pause should happen.
+	RefillDuration() time.Duration // Duration for the remaining points to refill back to full.
+	Current() int32                // Remaining points, as currently estimated.
+	Max() int32                    // Maximum points available.
+}
+
+// Max returns the maximum points available, the Limit, for BalanceModel.
+func (b *Balance) Max() int32 {
+	return b.limit()
+}
+
+// NewSemaphoreModel returns a pointer to Semaphore in the same way as
+// NewSemaphore, but consumes the point balance model (m) instead of a
+// Balance. Unless the model is a Balance, the Semaphore's Balance is nil,
+// so its fields and methods, such as Remaining, must not be used.
+func NewSemaphoreModel(cap int, m BalanceModel, opts ...func(*Semaphore)) *Semaphore {
+	return newSemaphoreFrom(context.Background(), cap, m, opts...)
+}
+
+// remaining returns the remaining points reported to the model, such as for
+// Stats and hooks. For a Balance, this is the last updated value rather than
+// the estimate of Current. It must be called with a model set.
+func (sem *Semaphore) remaining() int32 {
+	if b, ok := sem.model.(*Balance); ok {
+		return b.Remaining.Load()
+	}
+	return sem.model.Current()
+}
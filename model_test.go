@@ -0,0 +1,49 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// windowModel is a BalanceModel of a fixed window, allowing a number
+// of points per window.
+type windowModel struct {
+	pts    int32         // Points remaining in the window.
+	max    int32         // Points allowed per window.
+	window time.Duration // Duration of the window.
+}
+
+func (m *windowModel) Update(points int32) {
+	if points > ErrPts {
+		m.pts = points
+	}
+}
+func (m *windowModel) AtThreshold() bool             { return m.pts == 0 }
+func (m *windowModel) RefillDuration() time.Duration { return m.window }
+func (m *windowModel) Current() int32                { return m.pts }
+func (m *windowModel) Max() int32                    { return m.max }
+
+// TestSemaphoreModel should pause based on an alternative BalanceModel.
+func TestSemaphoreModel(t *testing.T) {
+	paused := make(chan time.Duration, 1)
+	ctx := context.Background()
+	m := &windowModel{pts: 10, max: 10, window: 20 * time.Millisecond}
+	sema := NewSemaphoreModel(1, m, WithPauseFunc(func(_ int32, dur time.Duration) {
+		paused <- dur
+	}))
+	if sema.Balance != nil {
+		t.Errorf("Balance = %v; want nil", sema.Balance)
+	}
+
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(0)
+	if dur := <-paused; dur != m.window {
+		t.Errorf("PauseFunc(_, %v); want PauseFunc(_, %v)", dur, m.window)
+	}
+	if st := sema.Stats(); st.Remaining != 0 || !st.Paused {
+		t.Errorf("Stats() = %+v; want 0 remaining and paused", st)
+	}
+}
@@ -0,0 +1,35 @@
+package shopifysemaphore
+
+// Overdraft represents the behavior of a Balance when optimistic consumption,
+// through Consume, would take the remaining points below zero.
+type Overdraft int
+
+const (
+	OverdraftClamp Overdraft = iota // Clamp the remaining points at zero, the default.
+	OverdraftAllow                  // Allow the remaining points below zero, down to the OverdraftLimit.
+	OverdraftBlock                  // Consume nothing if the remaining points can not cover the cost.
+)
+
+// String returns the name of the overdraft behavior.
+func (o Overdraft) String() string {
+	switch o {
+	case OverdraftClamp:
+		return "clamp"
+	case OverdraftAllow:
+		return "allow"
+	case OverdraftBlock:
+		return "block"
+	}
+	return "unknown"
+}
+
+// WithOverdraft is a functional option for Balance which will set the
+// behavior (mode) when consuming more points than remain. The limit is
+// how far below zero the remaining points may go for OverdraftAllow, and
+// is ignored otherwise.
+func WithOverdraft(mode Overdraft, limit int32) func(*Balance) {
+	return func(b *Balance) {
+		b.Overdraft = mode
+		b.OverdraftLimit = limit
+	}
+}
@@ -0,0 +1,57 @@
+package shopifysemaphore
+
+import (
+	"testing"
+	"time"
+)
+
+// TestOverdraft should follow the overdraft behavior when consuming
+// more points than remain.
+func TestOverdraft(t *testing.T) {
+	tests := []struct {
+		mode  Overdraft
+		expts int32
+		exok  bool
+	}{
+		{OverdraftClamp, 0, true},
+		{OverdraftAllow, -50, true},
+		{OverdraftBlock, 100, false},
+	}
+	for _, tt := range tests {
+		b := NewBalance(0, 1000, 0, WithOverdraft(tt.mode, 50))
+		b.Update(100)
+		if pts, ok := b.TryConsume(200); pts != tt.expts || ok != tt.exok {
+			t.Errorf("%v: Balance.TryConsume(200) = %d, %v; want %d, %v", tt.mode, pts, ok, tt.expts, tt.exok)
+		}
+	}
+
+	// Reserve can use the overdraft, but no further.
+	b := NewBalance(0, 1000, 0, WithOverdraft(OverdraftAllow, 50))
+	b.Update(100)
+	if _, ok := b.Reserve(150); !ok {
+		t.Errorf("Balance.Reserve(150) = _, false; want true")
+	}
+	if _, ok := b.Reserve(1); ok {
+		t.Errorf("Balance.Reserve(1) = _, true; want false")
+	}
+	if pts := b.Remaining.Load(); pts != -50 {
+		t.Errorf("Balance.Remaining = %d; want -50", pts)
+	}
+}
+
+// TestOverdraftBlockRefilled should never store negative remaining points,
+// even when admitted against the points refilled since the last update.
+func TestOverdraftBlockRefilled(t *testing.T) {
+	b := NewBalance(0, 1000, 100, WithOverdraft(OverdraftBlock, 0))
+	b.Update(0)
+	b.updatedAt.Store(time.Now().Add(-500 * time.Millisecond).UnixNano())
+	if pts, ok := b.TryConsume(40); pts < 10 || pts > 11 || !ok {
+		t.Errorf("Balance.TryConsume(40) = %d, %v; want 10, true", pts, ok)
+	}
+	if pts, ok := b.TryConsume(40); ok || pts < 0 {
+		t.Errorf("Balance.TryConsume(40) = %d, %v; want 10, false", pts, ok)
+	}
+	if pts := b.Remaining.Load(); pts < 0 {
+		t.Errorf("Balance.Remaining = %d; want 0 or more", pts)
+	}
+}
@@ -0,0 +1,15 @@
+package shopifysemaphore
+
+import "context"
+
+// AcquireClass will attempt to acquire a spot to run the Goroutine on behalf
+// of the partition class, set by WithSpotShares, returning a Lease which must
+// be used to release the spot. Classes take turns being granted spots, with
+// each class entitled to its share of the capacity, rather than of the
+// point balance. A class may borrow spots
+// beyond its share while they would otherwise go unused, and those spots are
+// handed back to classes within their share as they are released. A class
+// which is not partitioned has no share, and can only borrow.
+func (sem *Semaphore) AcquireClass(ctx context.Context, class string) (*Lease, error) {
+	return sem.lease(ctx, class)
+}
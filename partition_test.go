@@ -0,0 +1,61 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// TestAcquireClass should grant waiters within their class's share
+// before classes which are borrowing beyond theirs.
+func TestAcquireClass(t *testing.T) {
+	var mu sync.Mutex
+	var order []string // Order in which classes acquired.
+	var wg sync.WaitGroup
+
+	ctx := context.Background()
+	sema := newSemaphore(2, WithSpotShares(map[string]float64{
+		"sync":  0.5,
+		"adhoc": 0.5,
+	}))
+
+	// Sync borrows both spots as adhoc is not using its share.
+	var ls []*Lease
+	for i := 0; i < 2; i += 1 {
+		l, err := sema.AcquireClass(ctx, "sync")
+		if err != nil {
+			t.Fatalf("AcquireClass(%q, sync) = %v; want nil", ctx, err)
+		}
+		ls = append(ls, l)
+	}
+
+	// Queue sync ahead of adhoc, adhoc should still be granted first as
+	// sync is beyond its share.
+	for i, class := range []string{"sync", "adhoc"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l, err := sema.AcquireClass(ctx, class)
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			order = append(order, class)
+			mu.Unlock()
+
+			l.Release(1000)
+		}()
+		waitFor(sema, i+1)
+	}
+	ls[0].Release(1000)
+	wg.Wait()
+	ls[1].Release(1000)
+
+	if len(order) != 2 || order[0] != "adhoc" {
+		t.Errorf("order = %v; want [adhoc sync]", order)
+	}
+	if n := sema.classes["sync"] + sema.classes["adhoc"]; n != 0 {
+		t.Errorf("classes = %v; want none held", sema.classes)
+	}
+}
@@ -0,0 +1,38 @@
+package shopifysemaphore
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PartnerHost is the host of the Shopify Partner API.
+const PartnerHost = "partners.shopify.com"
+
+// PartnerRequestsPerSecond is the number of requests per second the Partner
+// API allows before responding with 429 Too Many Requests. Unlike the Admin
+// API, the Partner API counts requests rather than a point balance.
+const PartnerRequestsPerSecond = 4
+
+// IsPartnerRequest returns true if the request (req) is to the Shopify
+// Partner API, such as https://partners.shopify.com/1234/api/2024-01/graphql.json.
+func IsPartnerRequest(req *http.Request) bool {
+	return req.URL.Hostname() == PartnerHost && strings.HasSuffix(req.URL.Path, "/graphql.json")
+}
+
+// NewPartnerTransport returns a pointer to Transport, regulating requests to
+// the Partner API made by the base transport (base) through the Semaphore
+// (sem). As the Partner API reports no point balance, use it with
+// WithPartnerRateLimit to stay under the request rate, pausing for the
+// Retry-After of the response if throttled regardless. A nil base uses
+// http.DefaultTransport.
+func NewPartnerTransport(sem *Semaphore, base http.RoundTripper) *Transport {
+	return &Transport{Sem: sem, Base: base, Match: IsPartnerRequest}
+}
+
+// WithPartnerRateLimit is a functional option for Semaphore which will space
+// out spots being granted to stay under PartnerRequestsPerSecond, through
+// WithMinInterval.
+func WithPartnerRateLimit() func(*Semaphore) {
+	return WithMinInterval(time.Second / PartnerRequestsPerSecond)
+}
@@ -0,0 +1,49 @@
+package shopifysemaphore
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestIsPartnerRequest should match requests to the Partner API only.
+func TestIsPartnerRequest(t *testing.T) {
+	tests := []struct {
+		url  string // URL requested.
+		exok bool   // Expected to match.
+	}{
+		{"https://partners.shopify.com/1234/api/2024-01/graphql.json", true},
+		{"https://partners.shopify.com/1234/apps", false},
+		{"https://example.myshopify.com/admin/api/2024-01/graphql.json", false},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodPost, tt.url, nil)
+		if ok := IsPartnerRequest(req); ok != tt.exok {
+			t.Errorf("IsPartnerRequest(%q) = %v; want %v", tt.url, ok, tt.exok)
+		}
+	}
+}
+
+// TestPartnerTransport should space out Partner requests, and pause for the
+// Retry-After once throttled.
+func TestPartnerTransport(t *testing.T) {
+	sema := newSemaphore(2, WithPartnerRateLimit())
+	if sema.MinInterval != 250*time.Millisecond {
+		t.Errorf("MinInterval = %v; want 250ms", sema.MinInterval)
+	}
+
+	paused := make(chan time.Duration, 1)
+	sema.SetPauseFunc(func(_ int32, dur time.Duration) { paused <- dur })
+	client := &http.Client{Transport: NewPartnerTransport(sema, respond(429, "Retry-After", "1", "Too Many Requests"))}
+	resp, err := client.Post("https://partners.shopify.com/1234/api/2024-01/graphql.json", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Post() = %v; want nil", err)
+	}
+	resp.Body.Close()
+	if dur := <-paused; dur != time.Second {
+		t.Errorf("PauseFunc(_, %v); want PauseFunc(_, 1s)", dur)
+	}
+	if st := sema.Stats(); st.InFlight != 0 || st.LastErr != ErrThrottled {
+		t.Errorf("Stats() = %+v; want released with %v", st, ErrThrottled)
+	}
+}
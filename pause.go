@@ -0,0 +1,256 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"time"
+)
+
+// PauseReason represents why a pause happened, so logging and alerting can
+// distinguish expected pacing from real incidents.
+type PauseReason int
+
+const (
+	PauseThresholdReached PauseReason = iota // Remaining points reached the threshold, or a pausing Tier.
+	PauseManual                              // Paused through PauseFor.
+	PauseThrottled                           // A request was released with ErrThrottled.
+	PauseCircuitOpen                         // A circuit breaker has opened.
+)
+
+// String returns the name of the reason.
+func (r PauseReason) String() string {
+	switch r {
+	case PauseThresholdReached:
+		return "threshold reached"
+	case PauseManual:
+		return "manual"
+	case PauseThrottled:
+		return "throttled"
+	case PauseCircuitOpen:
+		return "circuit open"
+	}
+	return "unknown"
+}
+
+// PauseInfo represents a pause, passed to the PauseInfoFunc and other pause
+// hooks, carrying the context of the pause so hooks do not need to re-derive it.
+type PauseInfo struct {
+	Reason    PauseReason   // Why the pause happened.
+	Remaining int32         // Point balance remaining when paused.
+	Duration  time.Duration // Duration of the pause.
+	At        time.Time     // When the pause happened.
+	Waiters   int           // Number of Goroutines waiting for a spot when paused.
+	Shop      string        // Shop the Semaphore is for, as set by WithShop.
+}
+
+// PauseFor will pause the Semaphore for the duration (dur) in the same way
+// as reaching the threshold would, such as when an operator knows the shop
+// is being drained by another app. It will return false if the Semaphore is
+// already paused, in which case this is a no-op.
+func (sem *Semaphore) PauseFor(dur time.Duration) bool {
+	defer sem.mu.Unlock()
+	sem.mu.Lock()
+	if sem.paused {
+		return false
+	}
+	sem.pause(sem.remaining(), dur, PauseManual)
+	return true
+}
+
+// SetPauseInfoFunc will safely replace the PauseInfoFunc while the Semaphore
+// is in use. Writing the PauseInfoFunc field directly once the Semaphore is
+// in use is racy.
+func (sem *Semaphore) SetPauseInfoFunc(fn func(PauseInfo)) {
+	defer sem.mu.Unlock()
+	sem.mu.Lock()
+	WithPauseInfoFunc(fn)(sem)
+}
+
+// WithPauseInfoFunc is a functional option for Semaphore to call when
+// a pause happens, in addition to the PauseFunc. A PauseInfo, including
+// the reason for the pause, will be passed into the function.
+func WithPauseInfoFunc(fn func(PauseInfo)) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.PauseInfoFunc = fn
+	}
+}
+
+// WithPauseFuncCtx is a functional option for Semaphore to call when a pause
+// happens, in addition to the PauseFunc. The context passed in is the
+// Semaphore's lifecycle, as set by NewSemaphoreWithContext, so long-running
+// callbacks, such as posting to a chat service, can be cancelled at shutdown.
+func WithPauseFuncCtx(fn func(context.Context, PauseInfo)) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.PauseFuncCtx = fn
+	}
+}
+
+// WithResumeFuncCtx is a functional option for Semaphore to call when resume
+// from a pause happens, in addition to the ResumeFunc. The context passed in
+// is the Semaphore's lifecycle, in the same way as WithPauseFuncCtx.
+func WithResumeFuncCtx(fn func(context.Context)) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.ResumeFuncCtx = fn
+	}
+}
+
+// ResumeInfo represents a pause which has been resumed from, passed to the
+// ResumeInfoFunc, so the pause can be analysed without correlating the pause
+// and resume callbacks by time.
+type ResumeInfo struct {
+	Reason   PauseReason   // Why the pause happened.
+	Duration time.Duration // Actual duration paused for.
+	Before   int32         // Point balance remaining when paused.
+	After    int32         // Point balance remaining when resumed.
+	Waiters  int           // Number of Goroutines waiting for a spot when resumed.
+	Shop     string        // Shop the Semaphore is for, as set by WithShop.
+}
+
+// WithResumeFuncInfo is a functional option for Semaphore to call when resume
+// from a pause happens, in addition to the ResumeFunc. A ResumeInfo, with
+// details of the pause, will be passed into the function.
+func WithResumeFuncInfo(fn func(ResumeInfo)) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.ResumeInfoFunc = fn
+	}
+}
+
+// pauseInfo returns the PauseInfo for a pause of the duration (dur) for the
+// reason, at the point balance (pts). If the point balance is not known,
+// such as for a failed request, the remaining points are used instead.
+// It must be called while holding mu.
+func (sem *Semaphore) pauseInfo(pts int32, dur time.Duration, reason PauseReason) PauseInfo {
+	if pts == ErrPts {
+		pts = sem.remaining()
+	}
+	return PauseInfo{
+		Reason:    reason,
+		Remaining: pts,
+		Duration:  dur,
+		At:        time.Now(),
+		Waiters:   sem.queue.len(),
+		Shop:      sem.Shop,
+	}
+}
+
+// decide returns the duration (dur) of a pause for the reason, at the point
+// balance (pts), overridden by the PauseDecider if set and it returns a
+// duration greater than 0. It must be called while holding mu.
+func (sem *Semaphore) decide(pts int32, dur time.Duration, reason PauseReason) time.Duration {
+	if sem.PauseDecider == nil {
+		return dur
+	}
+	if d := sem.PauseDecider(sem.pauseInfo(pts, dur, reason)); d > 0 {
+		return d
+	}
+	return dur
+}
+
+// WithPauseDecider is a functional option for Semaphore which will allow the
+// duration of a pause to be overridden, such as extending it while an external
+// system reports the shop is under heavy merchant traffic. The function is
+// passed the PauseInfo of the pause, with the computed duration, and returns
+// the duration to pause for instead, or 0 to keep the computed duration. Any
+// MaxPause still applies. It is not called for PauseFor. It is called while
+// the Semaphore is locked, so it must not call back into the Semaphore.
+func WithPauseDecider(fn func(PauseInfo) time.Duration) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.PauseDecider = fn
+	}
+}
+
+// PauseRecord represents a single pause, as recorded by WithPauseHistory.
+type PauseRecord struct {
+	PauseInfo
+
+	Actual time.Duration // Actual duration paused for, 0 if still paused.
+}
+
+// PauseHistory returns the last pauses, oldest first, up to the number set
+// by WithPauseHistory, so how often and why the Semaphore was pausing can be
+// seen without external log aggregation. It returns nil if WithPauseHistory
+// was not used.
+func (sem *Semaphore) PauseHistory() []PauseRecord {
+	defer sem.mu.Unlock()
+	sem.mu.Lock()
+	n := cap(sem.pauseHist)
+	if n == 0 {
+		return nil
+	}
+	if len(sem.pauseHist) < n {
+		return append([]PauseRecord(nil), sem.pauseHist...)
+	}
+
+	// Full, the oldest is next to be overwritten.
+	i := sem.pauseHistN % n
+	return append(append([]PauseRecord(nil), sem.pauseHist[i:]...), sem.pauseHist[:i]...)
+}
+
+// recordPause will append the pause (info) into the pause history,
+// overwriting the oldest once full. It must be called while holding mu.
+func (sem *Semaphore) recordPause(info PauseInfo) {
+	n := cap(sem.pauseHist)
+	if n == 0 {
+		return
+	}
+	rec := PauseRecord{PauseInfo: info}
+	if len(sem.pauseHist) < n {
+		sem.pauseHist = append(sem.pauseHist, rec)
+	} else {
+		sem.pauseHist[sem.pauseHistN%n] = rec
+	}
+	sem.pauseHistN += 1
+}
+
+// recordResume will set the actual duration (dur) paused for on the latest
+// pause in the pause history. It must be called while holding mu.
+func (sem *Semaphore) recordResume(dur time.Duration) {
+	n := cap(sem.pauseHist)
+	if n == 0 || sem.pauseHistN == 0 {
+		return
+	}
+	sem.pauseHist[(sem.pauseHistN-1)%n].Actual = dur
+}
+
+// WithPauseHistory is a functional option for Semaphore which will keep
+// the last number (n) of pauses, available through PauseHistory.
+func WithPauseHistory(n int) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.pauseHist = make([]PauseRecord, 0, max(n, 0))
+		sem.pauseHistN = 0
+	}
+}
+
+// closedCh is a closed channel, returned by ResumeCh while not paused.
+var closedCh = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// ResumeCh returns a channel which is closed on the next resume from a pause,
+// broadcasting it to every receiver, so a component such as a job scheduler
+// holding deferred work can wait for it in a select. If the Semaphore is not
+// paused, the channel returned is already closed. A new channel is returned
+// for each pause, so ResumeCh should be called again once it has been closed.
+// The channel is also closed if the Semaphore's lifecycle ends during a pause.
+func (sem *Semaphore) ResumeCh() <-chan struct{} {
+	defer sem.mu.Unlock()
+	sem.mu.Lock()
+	if !sem.paused {
+		return closedCh
+	}
+	if sem.resumeCh == nil {
+		sem.resumeCh = make(chan struct{})
+	}
+	return sem.resumeCh
+}
+
+// WithShop is a functional option for Semaphore which will set the shop it
+// is for, such as the myshopify.com domain, passed to the pause and resume
+// hooks in PauseInfo and ResumeInfo so hooks shared by many Semaphores can
+// tell them apart.
+func WithShop(shop string) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.Shop = shop
+	}
+}
@@ -0,0 +1,262 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestPauseInfoFunc should pass the reason for each pause.
+func TestPauseInfoFunc(t *testing.T) {
+	paused := make(chan PauseInfo, 1)
+	ctx := context.Background()
+	sema := newSemaphore(1, WithMaxPause(5*time.Millisecond), WithPauseInfoFunc(func(info PauseInfo) {
+		paused <- info
+	}))
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(0)
+	info := <-paused
+	if info.Reason != PauseThresholdReached {
+		t.Errorf("PauseInfo.Reason = %v; want %v", info.Reason, PauseThresholdReached)
+	}
+	if info.Remaining != 0 {
+		t.Errorf("PauseInfo.Remaining = %d; want 0", info.Remaining)
+	}
+	if info.Duration != 5*time.Millisecond {
+		t.Errorf("PauseInfo.Duration = %v; want 5ms", info.Duration)
+	}
+
+	// Already at the threshold, and throttled.
+	b := NewBalance(900, 1000, 100, WithInitialRemaining(0))
+	sema = NewSemaphore(1, b, WithMaxPause(5*time.Millisecond), WithPauseInfoFunc(func(info PauseInfo) {
+		paused <- info
+	}))
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.ReleaseWithErr(ErrThrottled)
+	if info := <-paused; info.Reason != PauseThrottled {
+		t.Errorf("PauseInfo.Reason = %v; want %v", info.Reason, PauseThrottled)
+	}
+}
+
+// TestPauseFor should pause manually, and not while already paused.
+func TestPauseFor(t *testing.T) {
+	paused := make(chan PauseInfo, 1)
+	resumed := make(chan bool, 1)
+	sema := newSemaphore(1, WithPauseInfoFunc(func(info PauseInfo) {
+		paused <- info
+	}), WithResumeFunc(func() {
+		resumed <- true
+	}))
+	if ok := sema.PauseFor(10 * time.Millisecond); !ok {
+		t.Fatalf("PauseFor(10ms) = false; want true")
+	}
+	if ok := sema.PauseFor(10 * time.Millisecond); ok {
+		t.Errorf("PauseFor(10ms) = true; want false while paused")
+	}
+	if info := <-paused; info.Reason != PauseManual {
+		t.Errorf("PauseInfo.Reason = %v; want %v", info.Reason, PauseManual)
+	}
+	select {
+	case <-resumed:
+	case <-time.After(time.Second):
+		t.Fatalf("ResumeFunc not called; want called")
+	}
+}
+
+// TestPauseFuncCtx should cancel the callback's context with the
+// Semaphore's lifecycle, and run the resume callback with it.
+func TestPauseFuncCtx(t *testing.T) {
+	done := make(chan error, 1)
+	resumed := make(chan error, 1)
+	sctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sema := NewSemaphoreWithContext(sctx, 1, NewBalance(900, 1000, 100), WithPauseFuncCtx(func(ctx context.Context, info PauseInfo) {
+		<-ctx.Done()
+		done <- ctx.Err()
+	}), WithResumeFuncCtx(func(ctx context.Context) {
+		resumed <- ctx.Err()
+	}))
+
+	if ok := sema.PauseFor(time.Millisecond); !ok {
+		t.Fatalf("PauseFor(1ms) = false; want true")
+	}
+	if err := <-resumed; err != nil {
+		t.Errorf("ResumeFuncCtx(ctx) ctx.Err() = %v; want nil", err)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("PauseFuncCtx(ctx, _) ctx.Err() = %v; want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("PauseFuncCtx not cancelled; want cancelled")
+	}
+}
+
+// TestResumeFuncInfo should pass the details of the pause on resume.
+func TestResumeFuncInfo(t *testing.T) {
+	resumed := make(chan ResumeInfo, 1)
+	ctx := context.Background()
+	sema := newSemaphore(1, WithResumeFuncInfo(func(info ResumeInfo) {
+		resumed <- info
+	}))
+	sema.PauseFor(20 * time.Millisecond)
+
+	// Blocked for the pause.
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- sema.Acquire(ctx)
+	}()
+	waitFor(sema, 1)
+
+	info := <-resumed
+	if info.Reason != PauseManual {
+		t.Errorf("ResumeInfo.Reason = %v; want %v", info.Reason, PauseManual)
+	}
+	if info.Duration < 20*time.Millisecond {
+		t.Errorf("ResumeInfo.Duration = %v; want at least 20ms", info.Duration)
+	}
+	if info.Before != 1000 || info.After != 1000 {
+		t.Errorf("ResumeInfo{Before: %d, After: %d}; want {Before: 1000, After: 1000}", info.Before, info.After)
+	}
+	if info.Waiters != 1 {
+		t.Errorf("ResumeInfo.Waiters = %d; want 1", info.Waiters)
+	}
+	if err := <-acquired; err != nil {
+		t.Errorf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+}
+
+// TestPauseDecider should override the computed duration of a pause,
+// unless 0 is returned.
+func TestPauseDecider(t *testing.T) {
+	paused := make(chan time.Duration, 1)
+	ctx := context.Background()
+	var computed time.Duration
+	override := 5 * time.Millisecond
+	sema := newSemaphore(1, WithPauseDecider(func(info PauseInfo) time.Duration {
+		computed = info.Duration
+		return override
+	}), WithPauseFunc(func(_ int32, dur time.Duration) {
+		paused <- dur
+	}))
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(0)
+	if dur := <-paused; dur != override {
+		t.Errorf("PauseFunc(_, %v); want PauseFunc(_, %v)", dur, override)
+	}
+	if exdur := 10 * time.Second; computed != exdur {
+		t.Errorf("PauseInfo.Duration = %v; want %v", computed, exdur)
+	}
+
+	// Keep the computed duration, clamped by MaxPause.
+	sema = newSemaphore(1, WithMaxPause(5*time.Millisecond), WithPauseDecider(func(_ PauseInfo) time.Duration {
+		return 0
+	}), WithPauseFunc(func(_ int32, dur time.Duration) {
+		paused <- dur
+	}))
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(0)
+	if dur := <-paused; dur != 5*time.Millisecond {
+		t.Errorf("PauseFunc(_, %v); want PauseFunc(_, 5ms)", dur)
+	}
+}
+
+// TestPauseHistory should keep the last pauses, oldest first, with
+// the actual duration paused for.
+func TestPauseHistory(t *testing.T) {
+	if h := newSemaphore(1).PauseHistory(); h != nil {
+		t.Errorf("PauseHistory() = %v; want nil", h)
+	}
+
+	resumed := make(chan bool, 1)
+	sema := newSemaphore(1, WithPauseHistory(2), WithResumeFunc(func() {
+		resumed <- true
+	}))
+	for _, dur := range []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond} {
+		sema.PauseFor(dur)
+		<-resumed
+	}
+
+	h := sema.PauseHistory()
+	if len(h) != 2 {
+		t.Fatalf("len(PauseHistory()) = %d; want 2", len(h))
+	}
+	for i, exdur := range []time.Duration{2 * time.Millisecond, 3 * time.Millisecond} {
+		rec := h[i]
+		if rec.Duration != exdur || rec.Actual < exdur || rec.Reason != PauseManual || rec.At.IsZero() {
+			t.Errorf("PauseHistory()[%d] = %+v; want Duration %v, Reason %v", i, rec, exdur, PauseManual)
+		}
+	}
+}
+
+// TestResumeCh should be closed on resume, and already closed while
+// not paused.
+func TestResumeCh(t *testing.T) {
+	sema := newSemaphore(1)
+	select {
+	case <-sema.ResumeCh():
+	default:
+		t.Errorf("ResumeCh() open; want closed while not paused")
+	}
+
+	sema.PauseFor(10 * time.Millisecond)
+	ch := sema.ResumeCh()
+	if ch2 := sema.ResumeCh(); ch2 != ch {
+		t.Errorf("ResumeCh() = %v; want the same channel %v during a pause", ch2, ch)
+	}
+	select {
+	case <-ch:
+		t.Fatalf("ResumeCh() closed; want open while paused")
+	default:
+	}
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatalf("ResumeCh() not closed; want closed on resume")
+	}
+	if st := sema.Stats(); st.Paused {
+		t.Errorf("Stats().Paused = true; want false")
+	}
+}
+
+// TestPauseInfoContext should carry the waiters and shop of the pause
+// to the pause and resume hooks.
+func TestPauseInfoContext(t *testing.T) {
+	paused := make(chan PauseInfo, 1)
+	resumed := make(chan ResumeInfo, 1)
+	ctx := context.Background()
+	sema := newSemaphore(1, WithShop("example.myshopify.com"), WithMaxPause(5*time.Millisecond), WithPauseInfoFunc(func(info PauseInfo) {
+		paused <- info
+	}), WithResumeFuncInfo(func(info ResumeInfo) {
+		resumed <- info
+	}))
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- sema.Acquire(ctx)
+	}()
+	waitFor(sema, 1)
+
+	sema.Release(0)
+	if info := <-paused; info.Waiters != 1 || info.Shop != "example.myshopify.com" {
+		t.Errorf("PauseInfo = %+v; want Waiters 1, Shop example.myshopify.com", info)
+	}
+	if info := <-resumed; info.Shop != "example.myshopify.com" {
+		t.Errorf("ResumeInfo.Shop = %q; want example.myshopify.com", info.Shop)
+	}
+	if err := <-acquired; err != nil {
+		t.Errorf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+}
@@ -0,0 +1,182 @@
+package shopifysemaphore
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Bucket sizes and restore rates of the GraphQL Admin API for each plan, as
+// of the first API version. PresetFor accounts for the changes since.
+const (
+	StandardGraphQLLimit      int32   = 1000  // Bucket size for standard plans.
+	StandardGraphQLRefillRate float64 = 50    // Restore rate for standard plans, in points per second.
+	AdvancedGraphQLLimit      int32   = 2000  // Bucket size for Advanced plans.
+	AdvancedGraphQLRefillRate float64 = 100   // Restore rate for Advanced plans, in points per second.
+	PlusGraphQLLimit          int32   = 20000 // Bucket size for Shopify Plus.
+	PlusGraphQLRefillRate     float64 = 1000  // Restore rate for Shopify Plus, in points per second.
+)
+
+// Bucket sizes and leak rates of the REST Admin API for each plan.
+const (
+	StandardRESTLimit    int32   = 40  // Bucket size for standard plans, in calls.
+	StandardRESTLeakRate float64 = 2   // Leak rate for standard plans, in calls per second.
+	PlusRESTLimit        int32   = 400 // Bucket size for Shopify Plus, in calls.
+	PlusRESTLeakRate     float64 = 20  // Leak rate for Shopify Plus, in calls per second.
+)
+
+// GraphQLQueryMax is the maximum cost of a single GraphQL Admin API query,
+// regardless of plan.
+const GraphQLQueryMax int32 = 1000
+
+// DefaultThresholdPct is the fraction of the bucket size used as the
+// threshold by the plan presets.
+var DefaultThresholdPct = 0.1
+
+// NewStandardGraphQLBalance returns a pointer to Balance for the GraphQL
+// Admin API bucket of a standard plan, with a threshold of
+// DefaultThresholdPct of the bucket size, and a QueryMax of GraphQLQueryMax.
+func NewStandardGraphQLBalance(opts ...func(*Balance)) *Balance {
+	return NewBalancePct(DefaultThresholdPct, StandardGraphQLLimit, StandardGraphQLRefillRate, graphQLOpts(opts)...)
+}
+
+// NewAdvancedGraphQLBalance returns a pointer to Balance for the GraphQL
+// Admin API bucket of an Advanced plan, with a threshold of
+// DefaultThresholdPct of the bucket size, and a QueryMax of GraphQLQueryMax.
+func NewAdvancedGraphQLBalance(opts ...func(*Balance)) *Balance {
+	return NewBalancePct(DefaultThresholdPct, AdvancedGraphQLLimit, AdvancedGraphQLRefillRate, graphQLOpts(opts)...)
+}
+
+// NewPlusGraphQLBalance returns a pointer to Balance for the GraphQL
+// Admin API bucket of Shopify Plus, with a threshold of
+// DefaultThresholdPct of the bucket size, and a QueryMax of GraphQLQueryMax.
+func NewPlusGraphQLBalance(opts ...func(*Balance)) *Balance {
+	return NewBalancePct(DefaultThresholdPct, PlusGraphQLLimit, PlusGraphQLRefillRate, graphQLOpts(opts)...)
+}
+
+// graphQLOpts returns the options (opts) of a GraphQL preset, preceded by
+// the QueryMax of GraphQLQueryMax so it can be overridden.
+func graphQLOpts(opts []func(*Balance)) []func(*Balance) {
+	return append([]func(*Balance){WithQueryMax(GraphQLQueryMax)}, opts...)
+}
+
+// NewStandardRESTBucket returns a pointer to LeakyBucket for the REST Admin
+// API bucket of a standard plan, with a threshold of DefaultThresholdPct of
+// the bucket size. Use it with NewSemaphoreModel.
+func NewStandardRESTBucket() *LeakyBucket {
+	return NewLeakyBucket(int32(DefaultThresholdPct*float64(StandardRESTLimit)), StandardRESTLimit, StandardRESTLeakRate)
+}
+
+// NewPlusRESTBucket returns a pointer to LeakyBucket for the REST Admin
+// API bucket of Shopify Plus, with a threshold of DefaultThresholdPct of
+// the bucket size. Use it with NewSemaphoreModel.
+func NewPlusRESTBucket() *LeakyBucket {
+	return NewLeakyBucket(int32(DefaultThresholdPct*float64(PlusRESTLimit)), PlusRESTLimit, PlusRESTLeakRate)
+}
+
+// Plan represents a Shopify plan, which decides the size and restore rate
+// of the GraphQL Admin API bucket.
+type Plan int
+
+// Plans of PresetFor.
+const (
+	PlanStandard Plan = iota // Basic, Shopify, and other standard plans.
+	PlanAdvanced             // Advanced plan.
+	PlanPlus                 // Shopify Plus.
+)
+
+// String returns the name of the plan.
+func (p Plan) String() string {
+	switch p {
+	case PlanStandard:
+		return "standard"
+	case PlanAdvanced:
+		return "advanced"
+	case PlanPlus:
+		return "plus"
+	}
+	return fmt.Sprintf("Plan(%d)", int(p))
+}
+
+// ErrUnknownPreset is returned by PresetFor when there is no preset for the
+// API version or plan.
+var ErrUnknownPreset = errors.New("shopifysemaphore: unknown preset")
+
+// presetLimits holds the bucket of a plan within a presetEra.
+type presetLimits struct {
+	limit int32   // Bucket size.
+	rr    float64 // Restore rate, in points per second.
+}
+
+// presetEra holds the limits of the GraphQL Admin API from an API version
+// onwards, until the next era.
+type presetEra struct {
+	since    string                // First API version the era applies to.
+	queryMax int32                 // Maximum cost of a single query.
+	plans    map[Plan]presetLimits // Bucket of each plan.
+}
+
+// presetEras is the table of PresetFor, oldest first. A new era is added
+// once Shopify changes the limits with an API version.
+var presetEras = []presetEra{
+	{
+		since:    "2019-04",
+		queryMax: GraphQLQueryMax,
+		plans: map[Plan]presetLimits{
+			PlanStandard: {StandardGraphQLLimit, StandardGraphQLRefillRate},
+			PlanAdvanced: {AdvancedGraphQLLimit, AdvancedGraphQLRefillRate},
+			PlanPlus:     {PlusGraphQLLimit, PlusGraphQLRefillRate},
+		},
+	},
+	{
+		// Buckets and restore rates of standard and Advanced plans doubled.
+		since:    "2024-01",
+		queryMax: GraphQLQueryMax,
+		plans: map[Plan]presetLimits{
+			PlanStandard: {2 * StandardGraphQLLimit, 2 * StandardGraphQLRefillRate},
+			PlanAdvanced: {2 * AdvancedGraphQLLimit, 2 * AdvancedGraphQLRefillRate},
+			PlanPlus:     {PlusGraphQLLimit, PlusGraphQLRefillRate},
+		},
+	},
+}
+
+// versionRe matches an API version, such as "2024-07", with a month of 01
+// through 12.
+var versionRe = regexp.MustCompile(`^\d{4}-(0[1-9]|1[0-2])$`)
+
+// PresetFor returns a pointer to Balance for the GraphQL Admin API bucket of
+// the plan as of the API version (version), such as "2024-07", or "unstable"
+// for the latest. As with the plan presets, the threshold is DefaultThresholdPct
+// of the bucket size, and the QueryMax is the maximum cost of a single query
+// as of the version, which the options (opts) can override. It returns
+// ErrUnknownPreset for a version which is malformed or from before the GraphQL
+// Admin API was versioned, or a plan which is unknown.
+func PresetFor(version string, plan Plan, opts ...func(*Balance)) (*Balance, error) {
+	era, err := presetEraFor(version)
+	if err != nil {
+		return nil, err
+	}
+	lim, ok := era.plans[plan]
+	if !ok {
+		return nil, fmt.Errorf("%w: plan %v", ErrUnknownPreset, plan)
+	}
+	opts = append([]func(*Balance){WithQueryMax(era.queryMax)}, opts...)
+	return NewBalancePct(DefaultThresholdPct, lim.limit, lim.rr, opts...), nil
+}
+
+// presetEraFor returns the era of presetEras the API version applies to.
+func presetEraFor(version string) (presetEra, error) {
+	if version == "unstable" {
+		return presetEras[len(presetEras)-1], nil
+	}
+	if !versionRe.MatchString(version) {
+		return presetEra{}, fmt.Errorf("%w: version %q", ErrUnknownPreset, version)
+	}
+	for i := len(presetEras) - 1; i >= 0; i-- {
+		// Versions are YYYY-MM, so compare in order as strings.
+		if version >= presetEras[i].since {
+			return presetEras[i], nil
+		}
+	}
+	return presetEra{}, fmt.Errorf("%w: version %q", ErrUnknownPreset, version)
+}
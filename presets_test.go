@@ -0,0 +1,86 @@
+package shopifysemaphore
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestPresets should return balances for the bucket of each plan.
+func TestPresets(t *testing.T) {
+	tests := []struct {
+		name  string
+		b     *Balance
+		exlim int32
+		exrr  float64
+	}{
+		{"standard", NewStandardGraphQLBalance(), 1000, 50},
+		{"advanced", NewAdvancedGraphQLBalance(), 2000, 100},
+		{"plus", NewPlusGraphQLBalance(), 20000, 1000},
+	}
+	for _, tt := range tests {
+		if tt.b.Limit != tt.exlim || tt.b.RefillRate != tt.exrr || tt.b.Remaining.Load() != tt.exlim {
+			t.Errorf("%s = %d, %v, %d; want %d, %v, %d", tt.name, tt.b.Limit, tt.b.RefillRate, tt.b.Remaining.Load(), tt.exlim, tt.exrr, tt.exlim)
+		}
+		if exthld := tt.exlim / 10; tt.b.Threshold != exthld {
+			t.Errorf("%s Threshold = %d; want %d", tt.name, tt.b.Threshold, exthld)
+		}
+	}
+}
+
+// TestRESTPresets should return leaky buckets for the bucket of each plan.
+func TestRESTPresets(t *testing.T) {
+	tests := []struct {
+		name   string
+		lb     *LeakyBucket
+		exsize int32
+		exrate float64
+	}{
+		{"standard", NewStandardRESTBucket(), 40, 2},
+		{"plus", NewPlusRESTBucket(), 400, 20},
+	}
+	for _, tt := range tests {
+		if tt.lb.Size != tt.exsize || tt.lb.LeakRate != tt.exrate || tt.lb.Current() != tt.exsize {
+			t.Errorf("%s = %d, %v, %d; want %d, %v, %d", tt.name, tt.lb.Size, tt.lb.LeakRate, tt.lb.Current(), tt.exsize, tt.exrate, tt.exsize)
+		}
+		if exthld := tt.exsize / 10; tt.lb.Threshold != exthld {
+			t.Errorf("%s Threshold = %d; want %d", tt.name, tt.lb.Threshold, exthld)
+		}
+	}
+}
+
+// TestPresetFor should return balances for the plan as of the API version.
+func TestPresetFor(t *testing.T) {
+	tests := []struct {
+		version string  // API version.
+		plan    Plan    // Plan of the shop.
+		exlim   int32   // Expected limit.
+		exrr    float64 // Expected refill rate.
+		exerr   error   // Expected error.
+	}{
+		{"2023-10", PlanStandard, 1000, 50, nil},
+		{"2023-10", PlanAdvanced, 2000, 100, nil},
+		{"2024-07", PlanStandard, 2000, 100, nil},
+		{"2024-07", PlanAdvanced, 4000, 200, nil},
+		{"2019-04", PlanPlus, 20000, 1000, nil},
+		{"2024-07", PlanPlus, 20000, 1000, nil},
+		{"unstable", PlanStandard, 2000, 100, nil},
+		{"2019-01", PlanStandard, 0, 0, ErrUnknownPreset},
+		{"2024-7", PlanStandard, 0, 0, ErrUnknownPreset},
+		{"2031-99", PlanStandard, 0, 0, ErrUnknownPreset},
+		{"2024-00", PlanStandard, 0, 0, ErrUnknownPreset},
+		{"2024-07", Plan(9), 0, 0, ErrUnknownPreset},
+	}
+	for _, tt := range tests {
+		b, err := PresetFor(tt.version, tt.plan)
+		if !errors.Is(err, tt.exerr) {
+			t.Errorf("PresetFor(%q, %v) = %v; want %v", tt.version, tt.plan, err, tt.exerr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if b.Limit != tt.exlim || b.RefillRate != tt.exrr || b.QueryMax != GraphQLQueryMax || b.Threshold != tt.exlim/10 {
+			t.Errorf("PresetFor(%q, %v) = %d, %v, %d; want %d, %v, %d", tt.version, tt.plan, b.Limit, b.RefillRate, b.QueryMax, tt.exlim, tt.exrr, GraphQLQueryMax)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package shopifysemaphore
+
+import "container/list"
+
+// Priority represents the class of a request waiting for a spot. Waiters
+// of a higher priority are always granted a spot before waiters of a lower
+// priority, regardless of how long the lower priority waiters have waited.
+type Priority int
+
+const (
+	PriorityLow    Priority = iota // Background work, such as backfills.
+	PriorityNormal                 // Default priority used by Acquire.
+	PriorityHigh                   // Interactive work, such as a merchant requested sync.
+
+	numPriorities = int(PriorityHigh) + 1
+)
+
+// clamp will ensure the priority is within the known priority classes.
+func (p Priority) clamp() Priority {
+	if p < PriorityLow {
+		return PriorityLow
+	}
+	if p > PriorityHigh {
+		return PriorityHigh
+	}
+	return p
+}
+
+// waiter represents a Goroutine waiting in Acquire for a spot.
+type waiter struct {
+	ready chan struct{} // Closed once a spot has been granted.
+	prio  Priority      // Priority class of the waiter.
+	kq    *keyQueue     // Key queue the waiter belongs to.
+	elem  *list.Element // Position within the key queue.
+
+	class  string // Partition class the waiter is acquiring for, if any.
+	pauses int    // Number of pauses which had happened when granted.
+	err    error  // Error to return instead of a spot, if cancelled.
+}
+
+// keyQueue holds the waiters, in order, for a single key.
+type keyQueue struct {
+	key     string        // Key the waiters were queued with.
+	waiters list.List     // Waiters for the key, in order.
+	elem    *list.Element // Position within the priority level's rotation.
+}
+
+// front returns the waiter at the front of the key queue.
+func (kq *keyQueue) front() *waiter {
+	return kq.waiters.Front().Value.(*waiter)
+}
+
+// level holds the key queues for a single priority class. Keys take
+// turns, in a round-robin fashion, in having their front waiter granted.
+type level struct {
+	keys  list.List            // Key queues with waiters, in rotation order.
+	byKey map[string]*keyQueue // Key queues by key.
+}
+
+// waitQueue is an ordered queue of waiters. Waiters are granted spots by
+// priority. Within the same priority, keys are granted in a round-robin
+// fashion, and within the same key, in the same order they were requested
+// (FIFO). It is not safe for concurrent use and should be guarded by the
+// owning Semaphore's mutex.
+type waitQueue struct {
+	levels [numPriorities]level // Waiters for each priority class.
+	n      int                  // Total number of waiters.
+}
+
+// push will append a new waiter to the back of the queue for its priority
+// class and key, and return it.
+func (q *waitQueue) push(prio Priority, key string) *waiter {
+	prio = prio.clamp()
+	lvl := &q.levels[prio]
+	if lvl.byKey == nil {
+		lvl.byKey = make(map[string]*keyQueue)
+	}
+	kq, ok := lvl.byKey[key]
+	if !ok {
+		// First waiter for this key, join the back of the rotation.
+		kq = &keyQueue{key: key}
+		kq.elem = lvl.keys.PushBack(kq)
+		lvl.byKey[key] = kq
+	}
+
+	w := &waiter{ready: make(chan struct{}), prio: prio, kq: kq}
+	w.elem = kq.waiters.PushBack(w)
+	q.n += 1
+	return w
+}
+
+// pop will remove and return the front waiter of the next key in rotation,
+// for the highest priority class which has waiters. It will return nil if
+// the queue is empty.
+func (q *waitQueue) pop() *waiter {
+	return q.popPrefer(nil)
+}
+
+// popPrefer will remove and return a waiter in the same way as pop, but
+// within the highest priority class, the first key in rotation whose front
+// waiter is preferred (prefer returns true) is chosen. If no key is preferred,
+// the next key in rotation is chosen as with pop. A nil prefer prefers all.
+func (q *waitQueue) popPrefer(prefer func(*waiter) bool) *waiter {
+	for p := numPriorities - 1; p >= 0; p -= 1 {
+		lvl := &q.levels[p]
+		e := lvl.keys.Front()
+		if e == nil {
+			continue
+		}
+		if prefer != nil {
+			for pe := e; pe != nil; pe = pe.Next() {
+				if prefer(pe.Value.(*keyQueue).front()) {
+					e = pe
+					break
+				}
+			}
+		}
+
+		kq := e.Value.(*keyQueue)
+		w := kq.front()
+		q.remove(w)
+		if kq.elem != nil {
+			// Key still has waiters, send it to the back of the rotation.
+			lvl.keys.MoveToBack(kq.elem)
+		}
+		return w
+	}
+	return nil
+}
+
+// remove will remove the waiter from the queue, such as when a
+// context is cancelled before the waiter was granted a spot.
+func (q *waitQueue) remove(w *waiter) {
+	if w.elem == nil {
+		return
+	}
+
+	kq := w.kq
+	kq.waiters.Remove(w.elem)
+	w.elem = nil
+	q.n -= 1
+	if kq.waiters.Len() == 0 {
+		// No more waiters for this key, remove it from the rotation.
+		lvl := &q.levels[w.prio]
+		lvl.keys.Remove(kq.elem)
+		kq.elem = nil
+		delete(lvl.byKey, kq.key)
+	}
+}
+
+// len returns the number of waiters in the queue.
+func (q *waitQueue) len() int {
+	return q.n
+}
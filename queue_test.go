@@ -0,0 +1,69 @@
+package shopifysemaphore
+
+import "testing"
+
+// TestWaitQueue should ensure waiters come out of the queue in the
+// same order they went in, and removed waiters are skipped.
+func TestWaitQueue(t *testing.T) {
+	var q waitQueue
+	w1 := q.push(PriorityNormal, "")
+	w2 := q.push(PriorityNormal, "")
+	w3 := q.push(PriorityNormal, "")
+
+	q.remove(w2)
+	if l := q.len(); l != 2 {
+		t.Errorf("waitQueue.len() = %d; want 2", l)
+	}
+	if w := q.pop(); w != w1 {
+		t.Errorf("waitQueue.pop() = %p; want %p", w, w1)
+	}
+	if w := q.pop(); w != w3 {
+		t.Errorf("waitQueue.pop() = %p; want %p", w, w3)
+	}
+	if w := q.pop(); w != nil {
+		t.Errorf("waitQueue.pop() = %p; want nil", w)
+	}
+
+	// Removing an already popped waiter should be a no-op.
+	q.remove(w1)
+	if l := q.len(); l != 0 {
+		t.Errorf("waitQueue.len() = %d; want 0", l)
+	}
+}
+
+// TestWaitQueuePriority should ensure higher priority waiters come out
+// of the queue first, and in order within the same priority.
+func TestWaitQueuePriority(t *testing.T) {
+	var q waitQueue
+	low := q.push(PriorityLow, "")
+	norm := q.push(PriorityNormal, "")
+	high1 := q.push(PriorityHigh, "")
+	high2 := q.push(Priority(10), "") // Out of range, treated as PriorityHigh.
+
+	for i, exw := range []*waiter{high1, high2, norm, low} {
+		if w := q.pop(); w != exw {
+			t.Errorf("waitQueue.pop() #%d = %p; want %p", i, w, exw)
+		}
+	}
+}
+
+// TestWaitQueueKeyed should ensure keys take turns coming out of the
+// queue, with each key's waiters in order.
+func TestWaitQueueKeyed(t *testing.T) {
+	var q waitQueue
+	a1 := q.push(PriorityNormal, "a")
+	a2 := q.push(PriorityNormal, "a")
+	a3 := q.push(PriorityNormal, "a")
+	b1 := q.push(PriorityNormal, "b")
+	c1 := q.push(PriorityNormal, "c")
+	c2 := q.push(PriorityNormal, "c")
+
+	for i, exw := range []*waiter{a1, b1, c1, a2, c2, a3} {
+		if w := q.pop(); w != exw {
+			t.Errorf("waitQueue.pop() #%d = %p; want %p", i, w, exw)
+		}
+	}
+	if l := len(q.levels[PriorityNormal].byKey); l != 0 {
+		t.Errorf("len(byKey) = %d; want 0", l)
+	}
+}
@@ -0,0 +1,21 @@
+package shopifysemaphore
+
+import "time"
+
+// ramp returns the capacity (c) ramped up linearly from 1 to c over the
+// duration (dur) since start, along with how long until the next spot is
+// added. Once the duration has passed, c is returned with a step of 0.
+func ramp(c int, start time.Time, dur time.Duration) (int, time.Duration) {
+	elapsed := time.Since(start)
+	if c <= 1 || dur <= 0 || elapsed >= dur {
+		return c, 0
+	}
+
+	// Each spot beyond the first is added after an equal step of time.
+	step := dur / time.Duration(c-1)
+	if step <= 0 {
+		return c, 0
+	}
+	n := int(elapsed / step)
+	return 1 + n, time.Duration(n+1)*step - elapsed
+}
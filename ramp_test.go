@@ -0,0 +1,34 @@
+package shopifysemaphore
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRamp should ramp the capacity from 1 up to the full capacity
+// over the duration.
+func TestRamp(t *testing.T) {
+	dur := 10 * time.Second
+	tests := []struct {
+		ago  time.Duration // How long ago the ramp started.
+		exc  int           // Expected capacity.
+		step bool          // If a step until the next spot is expected.
+	}{
+		{0, 1, true},
+		{5 * time.Second, 3, true},
+		{9 * time.Second, 4, true},
+		{10 * time.Second, 5, false},
+		{time.Minute, 5, false},
+	}
+	for _, tt := range tests {
+		c, step := ramp(5, time.Now().Add(-tt.ago), dur)
+		if c != tt.exc || (step > 0) != tt.step {
+			t.Errorf("ramp(5, -%v, %v) = %d, %v; want %d, step %v", tt.ago, dur, c, step, tt.exc, tt.step)
+		}
+	}
+
+	// No ramp when there is no cap.
+	if c, step := ramp(0, time.Now(), dur); c != 0 || step != 0 {
+		t.Errorf("ramp(0, now, %v) = %d, %v; want 0, 0", dur, c, step)
+	}
+}
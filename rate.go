@@ -0,0 +1,72 @@
+package shopifysemaphore
+
+import (
+	"math"
+	"time"
+)
+
+// rateWindow is the window the consumption rate is averaged over. Older
+// observations decay in weight the further outside the window they are.
+const rateWindow = 10 * time.Second
+
+// consumption tracks a rolling average of points consumed per second,
+// derived from successive updates of the remaining points.
+type consumption struct {
+	pts  int32     // Remaining points of the last observation.
+	at   time.Time // When the last observation was made, zero if never.
+	rate float64   // Average points consumed per second.
+}
+
+// observe will account for the remaining points (pts) reported at now,
+// against the limit (lim) and the refill rate (rr). The points consumed
+// since the last observation are those which were expected, after
+// refilling, but are no longer remaining. It returns the points consumed,
+// and false if there was nothing to compare against.
+func (c *consumption) observe(pts int32, lim int32, rr float64, now time.Time) (float64, bool) {
+	last, at := c.pts, c.at
+	c.pts, c.at = pts, now
+	if at.IsZero() {
+		// First observation, nothing to compare against.
+		return 0, false
+	}
+	dt := now.Sub(at).Seconds()
+	expected := min(float64(last)+rr*max(dt, 0), float64(lim))
+	used := max(expected-float64(pts), 0)
+	if dt <= 0 {
+		return used, true
+	}
+
+	// Weight the sample by how much of the window it covers.
+	alpha := 1 - math.Exp(-dt/rateWindow.Seconds())
+	c.rate += alpha * (used/dt - c.rate)
+	return used, true
+}
+
+// ConsumptionRate returns a rolling average of the points consumed per
+// second, derived from successive calls to Update and accounting for the
+// points refilled in between. It returns 0 until at least two updates
+// have been observed.
+func (b *Balance) ConsumptionRate() float64 {
+	defer b.rmu.Unlock()
+	b.rmu.Lock()
+	return b.consumed.rate
+}
+
+// TimeToThreshold returns an estimate of how long until the threshold will
+// be reached, combining the current remaining points, the refill rate, and
+// the observed consumption rate, so work can be slowed down pre-emptively
+// instead of hitting a pause. It returns false if the points are not being
+// consumed faster than they refill, in which case the threshold would never
+// be reached. It returns 0 if already at the threshold.
+func (b *Balance) TimeToThreshold() (time.Duration, bool) {
+	if b.AtThreshold() {
+		return 0, true
+	}
+	drain := b.ConsumptionRate() - b.refillRate()
+	if drain <= 0 {
+		return 0, false
+	}
+
+	secs := float64(b.Current()-b.threshold()) / drain
+	return time.Duration(secs * float64(time.Second)), true
+}
@@ -0,0 +1,67 @@
+package shopifysemaphore
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestConsumption should average the points consumed per second,
+// accounting for points refilled between observations.
+func TestConsumption(t *testing.T) {
+	var c consumption
+	now := time.Now()
+	c.observe(100000, 100000, 50, now)
+	if c.rate != 0 {
+		t.Errorf("rate = %v; want 0", c.rate)
+	}
+
+	// 100 points refilled and 300 below the last observation, over 2s,
+	// is 200 points consumed per second. Repeated, it converges on it.
+	pts := int32(100000)
+	for i := 0; i < 50; i += 1 {
+		now = now.Add(2 * time.Second)
+		pts -= 300
+		c.observe(pts, 100000, 50, now)
+	}
+	if math.Abs(c.rate-200) > 1 {
+		t.Errorf("rate = %v; want near 200", c.rate)
+	}
+}
+
+// TestConsumptionRate should track the consumption rate from updates.
+func TestConsumptionRate(t *testing.T) {
+	b := NewBalance(0, 1000, 1)
+	if r := b.ConsumptionRate(); r != 0 {
+		t.Errorf("Balance.ConsumptionRate() = %v; want 0", r)
+	}
+	time.Sleep(10 * time.Millisecond)
+	b.Update(900)
+	if r := b.ConsumptionRate(); r <= 0 {
+		t.Errorf("Balance.ConsumptionRate() = %v; want above 0", r)
+	}
+}
+
+// TestTimeToThreshold should estimate when the threshold will be
+// reached from the consumption rate.
+func TestTimeToThreshold(t *testing.T) {
+	b := NewBalance(100, 1000, 50)
+	if _, ok := b.TimeToThreshold(); ok {
+		t.Errorf("Balance.TimeToThreshold() = _, true; want false")
+	}
+
+	// Consuming 250 points per second, refilling at 50, drains 200 per
+	// second, 600 points above the threshold takes 3s.
+	b.Update(700)
+	b.rmu.Lock()
+	b.consumed.rate = 250
+	b.rmu.Unlock()
+	if dur, ok := b.TimeToThreshold(); !ok || dur < 2900*time.Millisecond || dur > 3*time.Second {
+		t.Errorf("Balance.TimeToThreshold() = %v, %v; want 3s, true", dur, ok)
+	}
+
+	b.Update(100)
+	if dur, ok := b.TimeToThreshold(); !ok || dur != 0 {
+		t.Errorf("Balance.TimeToThreshold() = %v, %v; want 0s, true", dur, ok)
+	}
+}
@@ -0,0 +1,183 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrCostExceedsLimit is matched, through errors.Is, by the CostLimitError
+// returned by CheckCost and AcquireCost when the cost is more than a single
+// query may cost.
+var ErrCostExceedsLimit = errors.New("shopifysemaphore: cost exceeds the limit")
+
+// CostLimitError is returned by CheckCost and AcquireCost when the cost is
+// more than a single query may cost, the QueryMax or the Limit, so a query
+// which Shopify would reject fails locally instead of burning a request.
+type CostLimitError struct {
+	Cost int32 // Cost which was attempted.
+	Max  int32 // Maximum cost of a single query.
+}
+
+// Error returns the error message, including the cost and maximum.
+func (e *CostLimitError) Error() string {
+	return fmt.Sprintf("%s: cost %d, max %d", ErrCostExceedsLimit, e.Cost, e.Max)
+}
+
+// Unwrap returns ErrCostExceedsLimit so the error can be matched with errors.Is.
+func (e *CostLimitError) Unwrap() error {
+	return ErrCostExceedsLimit
+}
+
+// MaxQueryCost returns the maximum cost of a single query, the QueryMax, or
+// the Limit if lower or no QueryMax is set.
+func (b *Balance) MaxQueryCost() int32 {
+	lim := b.limit()
+	if b.QueryMax > 0 {
+		return min(b.QueryMax, lim)
+	}
+	return lim
+}
+
+// CheckCost returns a CostLimitError if the cost is more than a single query
+// may cost, as reported by MaxQueryCost, otherwise nil.
+func (b *Balance) CheckCost(cost int32) error {
+	if m := b.MaxQueryCost(); cost > m {
+		return &CostLimitError{Cost: cost, Max: m}
+	}
+	return nil
+}
+
+// Reservation represents points held tentatively by Balance.Reserve, for
+// a request whose actual cost is not yet known, such as a GraphQL query's
+// requestedQueryCost before its actualQueryCost is returned. It must be
+// settled once, with either Commit or Cancel.
+type Reservation struct {
+	b       *Balance     // Balance the points are held from.
+	cost    int32        // Points held.
+	settled *atomic.Bool // If the reservation has been committed or cancelled.
+}
+
+// Reserve will hold the cost in points from the remaining points, if the
+// current remaining points, accounting for those refilled since the last
+// update and any overdraft allowed by OverdraftAllow, can cover it. It
+// returns false, holding nothing, if they can not, or if the cost is more
+// than a single query may cost, as reported by CheckCost.
+func (b *Balance) Reserve(cost int32) (Reservation, bool) {
+	if b.CheckCost(cost) != nil {
+		return Reservation{}, false
+	}
+	if _, ok := b.consume(cost, true, SourceReserve); !ok {
+		return Reservation{}, false
+	}
+	return Reservation{b: b, cost: cost, settled: new(atomic.Bool)}, true
+}
+
+// Cost returns the points held by the Reservation.
+func (r Reservation) Cost() int32 {
+	return r.cost
+}
+
+// Commit will reconcile the Reservation with the actual cost, refunding the
+// points held beyond it, or consuming the shortfall if it was higher. It
+// returns false if the Reservation was already settled, or is empty.
+func (r Reservation) Commit(actual int32) bool {
+	if r.b == nil || !r.settled.CompareAndSwap(false, true) {
+		return false
+	}
+	switch {
+	case actual < r.cost:
+		r.b.Refund(r.cost - actual)
+	case actual > r.cost:
+		r.b.Consume(actual - r.cost)
+	}
+	return true
+}
+
+// Cancel will return every point held by the Reservation, such as when the
+// request failed before being run. It returns false if the Reservation was
+// already settled, or is empty.
+func (r Reservation) Cancel() bool {
+	return r.Commit(0)
+}
+
+// AcquireCost will acquire a spot in the same way as Acquire, and then reserve
+// the cost from the Balance, such as a GraphQL query's requestedQueryCost, so
+// other requests see the points as already spent. If the current remaining
+// points can not cover the cost, the spot is released and the Semaphore which
+// owns the point balance, the parent of a child, pauses for as long as the
+// refill rate takes to cover it before trying again. The
+// returned Reservation must be settled through ReleaseCost. A cost which is
+// more than a single query may cost, or more than could ever be reserved
+// within the Floor, returns a CostLimitError, and one over
+// the budget of WithBulkBudget a BulkError, without acquiring. For a Semaphore without a Balance, nothing is reserved and the
+// Reservation is empty.
+func (sem *Semaphore) AcquireCost(ctx context.Context, cost int32) (Reservation, error) {
+	if err := sem.CheckBudget(cost); err != nil {
+		return Reservation{}, err
+	}
+	b := sem.Balance
+	if b != nil {
+		if err := b.CheckCost(cost); err != nil {
+			return Reservation{}, err
+		}
+		if m := b.reservable(); cost > m {
+			// Could never be covered, even once refilled to the limit.
+			return Reservation{}, &CostLimitError{Cost: cost, Max: m}
+		}
+	}
+	for {
+		if err := sem.Acquire(ctx); err != nil {
+			return Reservation{}, err
+		}
+		if b == nil {
+			return Reservation{}, nil
+		}
+		if r, ok := b.Reserve(cost); ok {
+			return r, nil
+		}
+
+		// Wait out the shortfall, without the spot, and try again.
+		dur := DefaultPauseBuffer
+		if rr := b.refillRate(); rr > 0 {
+			short := cost + b.Floor - b.Current()
+			dur = time.Duration(float64(max(short, 1)) / rr * float64(time.Second))
+		}
+		owner := sem.owner()
+		owner.mu.Lock()
+		owner.hold(owner.remaining(), dur, PauseThresholdReached)
+		owner.mu.Unlock()
+		sem.Release(ErrPts)
+	}
+}
+
+// owner returns the Semaphore which owns the point balance and pausing, the
+// topmost parent of a child, or the Semaphore itself.
+func (sem *Semaphore) owner() *Semaphore {
+	for sem.parent != nil {
+		sem = sem.parent
+	}
+	return sem
+}
+
+// reservable returns the most points Reserve could ever hold, once refilled
+// to the limit, within the Floor and any overdraft allowed by OverdraftAllow.
+func (b *Balance) reservable() int32 {
+	m := b.limit() - b.Floor
+	if b.Overdraft == OverdraftAllow {
+		m += b.OverdraftLimit
+	}
+	return m
+}
+
+// ReleaseCost will settle the Reservation (r) of AcquireCost with the actual
+// cost, such as a GraphQL query's actualQueryCost, refunding or charging the
+// difference, and then release the spot with the remaining points (pts) in
+// the same way as Release. Passing ErrPts for the remaining points, such as
+// when the response did not report them, keeps the reconciled estimate.
+func (sem *Semaphore) ReleaseCost(r Reservation, actual int32, pts int32) {
+	r.Commit(actual)
+	sem.Release(pts)
+}
@@ -0,0 +1,163 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestReserve should hold points tentatively, reconciling them on
+// commit or returning them on cancel.
+func TestReserve(t *testing.T) {
+	b := NewBalance(0, 1000, 0)
+	r, ok := b.Reserve(300)
+	if !ok || r.Cost() != 300 {
+		t.Fatalf("Balance.Reserve(300) = %d, %v; want 300, true", r.Cost(), ok)
+	}
+	if pts := b.Remaining.Load(); pts != 700 {
+		t.Errorf("Balance.Remaining = %d; want 700", pts)
+	}
+
+	// Actual cost was lower, the difference is refunded.
+	if !r.Commit(100) {
+		t.Errorf("Reservation.Commit(100) = false; want true")
+	}
+	if pts := b.Remaining.Load(); pts != 900 {
+		t.Errorf("Balance.Remaining = %d; want 900", pts)
+	}
+	if r.Commit(100) || r.Cancel() {
+		t.Errorf("Reservation settled twice; want once")
+	}
+
+	// Can not cover the cost.
+	if _, ok := b.Reserve(1000); ok {
+		t.Errorf("Balance.Reserve(1000) = _, true; want false")
+	}
+
+	r, _ = b.Reserve(400)
+	r.Cancel()
+	if pts := b.Remaining.Load(); pts != 900 {
+		t.Errorf("Balance.Remaining = %d; want 900", pts)
+	}
+
+	// Actual cost was higher, the shortfall is consumed.
+	r, _ = b.Reserve(100)
+	r.Commit(250)
+	if pts := b.Remaining.Load(); pts != 650 {
+		t.Errorf("Balance.Remaining = %d; want 650", pts)
+	}
+	if (Reservation{}).Commit(1) {
+		t.Errorf("Reservation{}.Commit(1) = true; want false")
+	}
+}
+
+// TestAcquireCost should reserve the cost on acquire, and reconcile it with
+// the actual cost on release.
+func TestAcquireCost(t *testing.T) {
+	ctx := context.Background()
+	sema := NewSemaphore(2, NewBalance(100, 1000, 50))
+	r, err := sema.AcquireCost(ctx, 300)
+	if err != nil || r.Cost() != 300 {
+		t.Fatalf("AcquireCost(%q, 300) = %d, %v; want 300, nil", ctx, r.Cost(), err)
+	}
+	if pts := sema.Balance.Remaining.Load(); pts != 700 {
+		t.Errorf("Balance.Remaining = %d; want 700", pts)
+	}
+	sema.ReleaseCost(r, 120, ErrPts)
+	if pts := sema.Balance.Remaining.Load(); pts != 880 {
+		t.Errorf("Balance.Remaining = %d; want 880", pts)
+	}
+	if st := sema.Stats(); st.InFlight != 0 {
+		t.Errorf("Stats().InFlight = %d; want 0", st.InFlight)
+	}
+
+}
+
+// TestCheckCost should reject costs above what a single query may cost.
+func TestCheckCost(t *testing.T) {
+	ctx := context.Background()
+	sema := NewSemaphore(1, NewPlusGraphQLBalance())
+	if m := sema.Balance.MaxQueryCost(); m != GraphQLQueryMax {
+		t.Errorf("Balance.MaxQueryCost() = %d; want %d", m, GraphQLQueryMax)
+	}
+	if err := sema.Balance.CheckCost(1000); err != nil {
+		t.Errorf("Balance.CheckCost(1000) = %v; want nil", err)
+	}
+	if _, ok := sema.Balance.Reserve(1001); ok {
+		t.Error("Balance.Reserve(1001) = _, true; want false")
+	}
+
+	_, err := sema.AcquireCost(ctx, 1001)
+	var cle *CostLimitError
+	if !errors.As(err, &cle) || !errors.Is(err, ErrCostExceedsLimit) || cle.Cost != 1001 || cle.Max != 1000 {
+		t.Errorf("AcquireCost(%q, 1001) = %v; want %v", ctx, err, &CostLimitError{Cost: 1001, Max: 1000})
+	}
+	if st := sema.Stats(); st.Acquisitions != 0 {
+		t.Errorf("Stats().Acquisitions = %d; want 0", st.Acquisitions)
+	}
+
+	// Without a QueryMax, the limit applies.
+	b := NewBalance(0, 500, 50)
+	if err := b.CheckCost(501); !errors.Is(err, ErrCostExceedsLimit) {
+		t.Errorf("Balance.CheckCost(501) = %v; want %v", err, ErrCostExceedsLimit)
+	}
+}
+
+// TestAcquireCostShortfall should pause for the refill rate to cover the
+// cost when the remaining points can not.
+func TestAcquireCostShortfall(t *testing.T) {
+	paused := make(chan time.Duration, 1)
+	sema := NewSemaphore(1, NewBalance(0, 1000, 1000), WithPauseFunc(func(_ int32, dur time.Duration) {
+		paused <- dur
+	}))
+	sema.Balance.Update(50)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	r, err := sema.AcquireCost(ctx, 100)
+	if err != nil {
+		t.Fatalf("AcquireCost(%q, 100) = %v; want nil", ctx, err)
+	}
+	if dur := <-paused; dur <= 0 || dur > 50*time.Millisecond {
+		t.Errorf("PauseFunc(_, %v); want about 50ms", dur)
+	}
+	sema.ReleaseCost(r, 100, ErrPts)
+}
+
+// TestAcquireCostChild should release the spot on both the child and parent
+// while waiting out the shortfall, pausing the parent.
+func TestAcquireCostChild(t *testing.T) {
+	parent := NewSemaphore(2, NewBalance(0, 1000, 1000))
+	child := parent.Child(0.5)
+	parent.Balance.Update(50)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	r, err := child.AcquireCost(ctx, 100)
+	if err != nil {
+		t.Fatalf("AcquireCost(%q, 100) = %v; want nil", ctx, err)
+	}
+	if st := parent.Stats(); st.InFlight != 1 || st.Pauses != 1 {
+		t.Errorf("parent Stats() = %+v; want 1 in flight and 1 pause", st)
+	}
+	if st := child.Stats(); st.Pauses != 0 {
+		t.Errorf("child Stats().Pauses = %d; want 0", st.Pauses)
+	}
+	child.ReleaseCost(r, 100, ErrPts)
+	if st := parent.Stats(); st.InFlight != 0 {
+		t.Errorf("parent Stats().InFlight = %d; want 0", st.InFlight)
+	}
+}
+
+// TestAcquireCostUnreservable should reject a cost which could never be
+// reserved within the Floor.
+func TestAcquireCostUnreservable(t *testing.T) {
+	ctx := context.Background()
+	sema := NewSemaphore(1, NewStandardGraphQLBalance(WithReserve(200)))
+	_, err := sema.AcquireCost(ctx, 900)
+	var cle *CostLimitError
+	if !errors.As(err, &cle) || cle.Max != 800 {
+		t.Errorf("AcquireCost(%q, 900) = %v; want %v", ctx, err, &CostLimitError{Cost: 900, Max: 800})
+	}
+}
@@ -0,0 +1,106 @@
+package shopifysemaphore
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HeaderCallLimit is the header of REST Admin API responses reporting the
+// calls used out of the maximum, such as "32/40".
+const HeaderCallLimit = "X-Shopify-Shop-Api-Call-Limit"
+
+// ErrInvalidCallLimit is returned by ParseCallLimit when the value is not
+// in the form of used calls out of the maximum, such as "32/40".
+var ErrInvalidCallLimit = errors.New("shopifysemaphore: invalid call limit")
+
+// ParseCallLimit returns the calls used and the maximum calls from the
+// value (v) of the HeaderCallLimit header, such as 32 and 40 for "32/40".
+func ParseCallLimit(v string) (int32, int32, error) {
+	used, lim, ok := strings.Cut(v, "/")
+	if !ok {
+		return 0, 0, ErrInvalidCallLimit
+	}
+	u, err := strconv.ParseInt(strings.TrimSpace(used), 10, 32)
+	if err != nil {
+		return 0, 0, ErrInvalidCallLimit
+	}
+	m, err := strconv.ParseInt(strings.TrimSpace(lim), 10, 32)
+	if err != nil {
+		return 0, 0, ErrInvalidCallLimit
+	}
+	return int32(u), int32(m), nil
+}
+
+// LeakyBucket is a BalanceModel of the REST Admin API's leaky bucket, where
+// each call fills the bucket and it leaks, or restores, at a fixed rate of
+// calls per second. Its points are the calls remaining before the bucket is
+// full, as returned by ParseCallLimit's maximum less the used calls. It is
+// safe for concurrent use.
+type LeakyBucket struct {
+	Size      int32   // Maximum calls the bucket holds.
+	LeakRate  float64 // Number of calls leaked per second.
+	Threshold int32   // Calls remaining at or below which to pause.
+
+	mu   sync.Mutex // For handling the fill.
+	used int32      // Calls in the bucket, as of at.
+	at   time.Time  // When the calls in the bucket were last updated.
+}
+
+// NewLeakyBucket returns a pointer to LeakyBucket, accepting a threshold
+// (thld) of calls remaining, the bucket size (size), and the leak rate (rate)
+// in calls per second. The bucket starts empty.
+func NewLeakyBucket(thld int32, size int32, rate float64) *LeakyBucket {
+	return &LeakyBucket{Size: size, LeakRate: rate, Threshold: thld, at: time.Now()}
+}
+
+// Update will store the calls remaining (points) before the bucket is full,
+// ignoring ErrPts.
+func (lb *LeakyBucket) Update(points int32) {
+	if points <= ErrPts {
+		return
+	}
+	defer lb.mu.Unlock()
+	lb.mu.Lock()
+	lb.used = max(lb.Size-min(points, lb.Size), 0)
+	lb.at = time.Now()
+}
+
+// filled returns the calls in the bucket, as estimated from the leak since
+// the last update. It must be called while holding mu.
+func (lb *LeakyBucket) filled() float64 {
+	leaked := time.Since(lb.at).Seconds() * lb.LeakRate
+	return max(float64(lb.used)-leaked, 0)
+}
+
+// AtThreshold returns true if the calls remaining are at or below the
+// threshold.
+func (lb *LeakyBucket) AtThreshold() bool {
+	return lb.Current() <= lb.Threshold
+}
+
+// RefillDuration returns the duration for the bucket to leak empty.
+func (lb *LeakyBucket) RefillDuration() time.Duration {
+	if lb.LeakRate <= 0 {
+		return 0
+	}
+	defer lb.mu.Unlock()
+	lb.mu.Lock()
+	return time.Duration(lb.filled() / lb.LeakRate * float64(time.Second))
+}
+
+// Current returns the calls remaining before the bucket is full, as
+// estimated from the leak since the last update.
+func (lb *LeakyBucket) Current() int32 {
+	defer lb.mu.Unlock()
+	lb.mu.Lock()
+	return lb.Size - int32(math.Ceil(lb.filled()))
+}
+
+// Max returns the bucket size.
+func (lb *LeakyBucket) Max() int32 {
+	return lb.Size
+}
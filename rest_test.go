@@ -0,0 +1,70 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestParseCallLimit should parse the calls used and maximum from the header.
+func TestParseCallLimit(t *testing.T) {
+	tests := []struct {
+		v      string // Header value.
+		exused int32  // Expected calls used.
+		exlim  int32  // Expected maximum calls.
+		exerr  error  // Expected error.
+	}{
+		{"32/40", 32, 40, nil},
+		{" 40 / 40 ", 40, 40, nil},
+		{"", 0, 0, ErrInvalidCallLimit},
+		{"a/40", 0, 0, ErrInvalidCallLimit},
+	}
+	for _, tt := range tests {
+		if used, lim, err := ParseCallLimit(tt.v); used != tt.exused || lim != tt.exlim || !errors.Is(err, tt.exerr) {
+			t.Errorf("ParseCallLimit(%q) = %d, %d, %v; want %d, %d, %v", tt.v, used, lim, err, tt.exused, tt.exlim, tt.exerr)
+		}
+	}
+}
+
+// TestLeakyBucket should estimate the calls remaining as the bucket leaks.
+func TestLeakyBucket(t *testing.T) {
+	lb := NewLeakyBucket(4, 40, 2)
+	lb.Update(ErrPts)
+	if pts := lb.Current(); pts != 40 {
+		t.Errorf("Current() = %d; want 40", pts)
+	}
+
+	lb.Update(2)
+	if !lb.AtThreshold() {
+		t.Error("AtThreshold() = false; want true")
+	}
+	if dur := lb.RefillDuration(); dur <= 18*time.Second || dur > 19*time.Second {
+		t.Errorf("RefillDuration() = %v; want about 19s", dur)
+	}
+
+	// Three seconds later, six calls have leaked.
+	lb.at = lb.at.Add(-3 * time.Second)
+	if pts := lb.Current(); pts != 8 {
+		t.Errorf("Current() = %d; want 8", pts)
+	}
+	if lb.AtThreshold() {
+		t.Error("AtThreshold() = true; want false")
+	}
+}
+
+// TestLeakyBucketSemaphore should pause until the bucket has leaked empty.
+func TestLeakyBucketSemaphore(t *testing.T) {
+	paused := make(chan time.Duration, 1)
+	ctx := context.Background()
+	sema := NewSemaphoreModel(1, NewStandardRESTBucket(), WithPauseFunc(func(_ int32, dur time.Duration) {
+		paused <- dur
+	}))
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(40 - 38)
+	if dur := <-paused; dur <= 18*time.Second || dur > 19*time.Second {
+		t.Errorf("PauseFunc(_, %v); want about 19s", dur)
+	}
+}
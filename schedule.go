@@ -0,0 +1,93 @@
+package shopifysemaphore
+
+import (
+	"slices"
+	"time"
+)
+
+// Window represents a recurring window of time, set by WithSchedule, during
+// which the Semaphore yields API budget, such as during a merchant's business
+// hours or a flash sale, so heavy background work does not compete with it.
+type Window struct {
+	Start time.Duration  // Time of day the window starts, since midnight.
+	End   time.Duration  // Time of day the window ends, since midnight, before Start to span midnight.
+	Days  []time.Weekday // Days the window starts on, nil for every day.
+
+	Capacity     float64 // Fraction of the capacity allowed during the window, 0 for unchanged.
+	ThresholdPct float64 // Fraction of Limit at or below which to also pause during the window, 0 for unchanged.
+}
+
+// active returns true if the window applies at the time (t), in the
+// location of t, along with when it ends.
+func (w Window) active(t time.Time) (bool, time.Time) {
+	y, m, d := t.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	tod := t.Sub(midnight)
+
+	start := midnight.Add(w.Start)
+	switch {
+	case w.Start <= w.End && tod >= w.Start && tod < w.End:
+	case w.Start > w.End && tod >= w.Start:
+	case w.Start > w.End && tod < w.End:
+		// Spanning midnight, so started the day before.
+		start = start.AddDate(0, 0, -1)
+	default:
+		return false, time.Time{}
+	}
+	if w.Days != nil && !slices.Contains(w.Days, start.Weekday()) {
+		return false, time.Time{}
+	}
+	end := start.Add(w.End - w.Start)
+	if w.Start > w.End {
+		end = end.Add(24 * time.Hour)
+	}
+	return true, end
+}
+
+// schedule holds the windows set by WithSchedule.
+type schedule struct {
+	loc     *time.Location // Location the windows are in.
+	windows []Window       // Windows, the first active applies.
+}
+
+// window returns the first window active at now, along with when it ends,
+// or false if none are active. It must be called while holding mu.
+func (sem *Semaphore) window(now time.Time) (Window, time.Time, bool) {
+	if sem.schedule == nil {
+		return Window{}, time.Time{}, false
+	}
+	t := now.In(sem.schedule.loc)
+	for _, w := range sem.schedule.windows {
+		if ok, end := w.active(t); ok {
+			return w, end, true
+		}
+	}
+	return Window{}, time.Time{}, false
+}
+
+// scheduleModel is a BalanceModel which also reports being at the threshold
+// once the remaining points are at or below the ThresholdPct of a Window.
+type scheduleModel struct {
+	BalanceModel
+
+	pct float64 // Fraction of Max at or below which to pause.
+}
+
+// AtThreshold returns true if the model is at its threshold, or the remaining
+// points are at or below the window's fraction of the maximum.
+func (sm scheduleModel) AtThreshold() bool {
+	return sm.BalanceModel.AtThreshold() || float64(sm.Current()) <= sm.pct*float64(sm.Max())
+}
+
+// WithSchedule is a functional option for Semaphore which will set windows
+// of time, in the location (loc), during which the capacity is reduced or
+// the threshold is raised. The first matching window applies. A nil location
+// uses the local time.
+func WithSchedule(loc *time.Location, windows ...Window) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		if loc == nil {
+			loc = time.Local
+		}
+		sem.schedule = &schedule{loc: loc, windows: slices.Clone(windows)}
+	}
+}
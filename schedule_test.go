@@ -0,0 +1,55 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestWindowActive should apply the window between its start and end,
+// including when spanning midnight, on its days.
+func TestWindowActive(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) // Monday.
+	business := Window{Start: 9 * time.Hour, End: 17 * time.Hour, Days: []time.Weekday{time.Monday}}
+	night := Window{Start: 22 * time.Hour, End: 2 * time.Hour}
+	tests := []struct {
+		w     Window
+		at    time.Duration // Time since the start of the day.
+		exok  bool          // Expected to be active.
+		exend time.Duration // Expected end, since the start of the day.
+	}{
+		{business, 8 * time.Hour, false, 0},
+		{business, 12 * time.Hour, true, 17 * time.Hour},
+		{business, 17 * time.Hour, false, 0},
+		{business, 36 * time.Hour, false, 0}, // Tuesday.
+		{night, 23 * time.Hour, true, 26 * time.Hour},
+		{night, 25 * time.Hour, true, 26 * time.Hour},
+		{night, 12 * time.Hour, false, 0},
+	}
+	for _, tt := range tests {
+		ok, end := tt.w.active(day.Add(tt.at))
+		if ok != tt.exok || (ok && !end.Equal(day.Add(tt.exend))) {
+			t.Errorf("Window{%v, %v}.active(+%v) = %v, %v; want %v, +%v", tt.w.Start, tt.w.End, tt.at, ok, end, tt.exok, tt.exend)
+		}
+	}
+}
+
+// TestWithSchedule should reduce the capacity and raise the threshold
+// during an active window.
+func TestWithSchedule(t *testing.T) {
+	ctx := context.Background()
+	allDay := Window{Start: 0, End: 24 * time.Hour, Capacity: 0.5, ThresholdPct: 0.7}
+	sema := NewSemaphore(4, NewBalance(100, 1000, 1), WithMaxPause(time.Millisecond), WithSchedule(time.UTC, allDay))
+	if c := sema.Stats().Capacity; c != 2 {
+		t.Errorf("Stats().Capacity = %d; want 2", c)
+	}
+
+	// Well above the threshold of 100, but not the window's 700.
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(600)
+	if st := sema.Stats(); !st.Paused {
+		t.Errorf("Stats().Paused = false; want true")
+	}
+}
@@ -2,44 +2,241 @@ package shopifysemaphore
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
 	"sync"
 	"time"
 )
 
 var (
-	DefaultAquireBuffer = 200 * time.Millisecond // Default aquire throttle duration.
-	DefaultPauseBuffer  = 1 * time.Second        // Default pause buffer to append to pause duration calculation.
+	DefaultAcquireBuffer = 200 * time.Millisecond // Default acquire throttle duration, currently unused.
+	DefaultPauseBuffer   = 1 * time.Second        // Default pause buffer to append to pause duration calculation.
+
+	// Deprecated: use DefaultAcquireBuffer.
+	DefaultAquireBuffer = DefaultAcquireBuffer
+)
+
+// ErrQueueFull is returned by Acquire when the maximum number of waiting
+// Goroutines, set by MaxWaiters, has been reached.
+var ErrQueueFull = errors.New("shopifysemaphore: waiter queue is full")
+
+// ErrDeadlineWouldExceed is returned by Acquire, wrapped in a PausedError,
+// when the Semaphore is paused and the pause would not end before the
+// context's deadline, so the caller does not spend its entire deadline
+// waiting for a spot it can not get.
+var ErrDeadlineWouldExceed = errors.New("shopifysemaphore: pause would exceed context deadline")
+
+// ErrMaxWaitExceeded is matched, through errors.Is, by the MaxWaitError
+// returned by Acquire when a spot would not be granted within MaxWait.
+var ErrMaxWaitExceeded = errors.New("shopifysemaphore: wait would exceed maximum")
+
+// MaxWaitError is returned by Acquire when a spot would not be granted
+// within MaxWait. Wait is the projected duration until a spot would be
+// granted, based on any pause in progress and pacing, so the caller can
+// defer the work, such as to a job queue, instead of holding on.
+type MaxWaitError struct {
+	Wait    time.Duration // Projected wait for a spot, 0 if unknown.
+	MaxWait time.Duration // Maximum wait which would have been exceeded.
+}
+
+// Error returns the error message, including the projected wait.
+func (e *MaxWaitError) Error() string {
+	return fmt.Sprintf("%s: projected %v, max %v", ErrMaxWaitExceeded, e.Wait, e.MaxWait)
+}
+
+// Unwrap returns ErrMaxWaitExceeded so the error can be matched with errors.Is.
+func (e *MaxWaitError) Unwrap() error {
+	return ErrMaxWaitExceeded
+}
+
+// PausedError is returned by Acquire when the caller gave up on a spot while
+// the Semaphore was paused, such as when its context's deadline fired, or
+// when the pause would outlast the deadline. It carries how much of the pause
+// remained and the point balance, so a retry can be scheduled for when the
+// pause ends instead of blindly re-attempting. Err is the reason given up,
+// such as context.DeadlineExceeded or ErrDeadlineWouldExceed, and can be
+// matched with errors.Is.
+type PausedError struct {
+	Err       error         // Reason the caller gave up.
+	Reason    PauseReason   // Why the pause happened.
+	Remaining time.Duration // Remaining duration of the pause.
+	Points    int32         // Point balance remaining.
+}
+
+// Error returns the error message, including the remaining pause.
+func (e *PausedError) Error() string {
+	return fmt.Sprintf("%s: paused for %v more, %d points remaining", e.Err, e.Remaining, e.Points)
+}
+
+// Unwrap returns the reason the caller gave up, so the error can be matched
+// with errors.Is.
+func (e *PausedError) Unwrap() error {
+	return e.Err
+}
+
+// pausedErr returns err wrapped in a PausedError if the Semaphore is paused,
+// otherwise err as is. It must be called while holding mu.
+func (sem *Semaphore) pausedErr(err error) error {
+	if !sem.paused {
+		return err
+	}
+	return &PausedError{
+		Err:       err,
+		Reason:    sem.pauseReason,
+		Remaining: max(time.Until(sem.pauseEnds), 0),
+		Points:    sem.remaining(),
+	}
+}
+
+// Configuration errors returned by NewSemaphoreE and NewBalanceE.
+var (
+	ErrNilBalance        = errors.New("shopifysemaphore: balance must not be nil")
+	ErrInvalidRefillRate = errors.New("shopifysemaphore: refill rate must be greater than zero")
+	ErrInvalidLimit      = errors.New("shopifysemaphore: limit must be greater than zero")
+	ErrInvalidThreshold  = errors.New("shopifysemaphore: threshold must be less than the limit")
 )
 
 // Semaphore is responsible regulating when to pause and resume processing of Goroutines.
 // Points remaining, point thresholds, and point refill rates are taken into
 // consideration. If remaining points go below the threshold, a pause is initiated
 // which will also calculate how long a pause should happen based on the refill rate.
-// Once pause is completed, the processing will resume. A PauceFunc and ResumeFunc
+// Once pause is completed, the processing will resume. A PauseFunc and ResumeFunc
 // can optionally be passed in which will fire respectively when a pause happens
-// and when a resume happens.
+// and when a resume happens. Spots are granted to waiting Goroutines in the order
+// they were requested.
 type Semaphore struct {
-	*Balance // Point information and tracking.
+	*Balance // Point information and tracking, nil if a BalanceModel other than Balance is used.
+
+	model BalanceModel // Point balance model consumed for pausing, the Balance unless set by NewSemaphoreModel.
+	Shop  string       // Shop the Semaphore is for, passed to hooks, empty if not set.
+
+	PauseFunc      func(int32, time.Duration)       // Optional callback for when pause happens.
+	ResumeFunc     func()                           // Optional callback for when resume happens.
+	PauseInfoFunc  func(PauseInfo)                  // Optional callback for when pause happens, with the reason.
+	PauseFuncCtx   func(context.Context, PauseInfo) // Optional callback for when pause happens, cancelled with the Semaphore's lifecycle.
+	ResumeFuncCtx  func(context.Context)            // Optional callback for when resume happens, cancelled with the Semaphore's lifecycle.
+	ResumeInfoFunc func(ResumeInfo)                 // Optional callback for when resume happens, with details of the pause.
+	PauseBuffer    time.Duration                    // Buffer of time to extend the pause with.
+	PauseStrategy  PauseStrategy                    // Policy of when to pause and for how long.
+	PausePredicate func(*Balance) bool              // Optional replacement of the AtThreshold check of when to pause.
+	PauseJitter    float64                          // Fraction of a pause it may be randomly extended by, 0 for none.
+	MaxPause       time.Duration                    // Maximum duration of a pause, 0 for no maximum.
+	PauseDecider   func(PauseInfo) time.Duration    // Optional override of the duration of a pause.
+	ResumeAbove    float64                          // Fraction of Limit the remaining points must recover to during a pause to resume early, 0 for never.
+	BeforeResume   func() bool                      // Optional approval of resuming once a pause is over.
+	ResumeRetry    time.Duration                    // Duration to wait to ask BeforeResume again after it declined.
+	PauseTick      time.Duration                    // Interval to re-evaluate the point balance during a pause, 0 to wait out the pause.
+	AcquireBuffer  time.Duration                    // Unused since spots are granted directly to waiters, retained for compatibility.
+	AquireBuffer   time.Duration                    // Deprecated: use AcquireBuffer.
+	MaxWaiters     int                              // Maximum number of Goroutines waiting in Acquire, 0 for unbounded.
+	MinInterval    time.Duration                    // Minimum spacing between spots being granted, 0 for none.
+	MaxWait        time.Duration                    // Maximum duration to wait in Acquire for a spot, 0 for unbounded.
+	Warmup         time.Duration                    // Duration to ramp up from 1 spot to full capacity after creation, 0 for none.
+	Cooldown       time.Duration                    // Duration to ramp up from 1 spot to full capacity after a pause, 0 for none.
+	SpotShares     map[string]float64               // Share of spots for each class used with AcquireClass.
+
+	adaptive *aimd     // Adaptive concurrency controller, nil if disabled.
+	breaker  *breaker  // Circuit breaker, nil if disabled.
+	schedule *schedule // Windows set by WithSchedule, nil if none.
+
+	softThrottle *SoftThrottle // Spacing set by WithSoftThrottle, nil if disabled.
+	bp           *backpressure // Backpressure signalling, nil if disabled.
+	tiers        []Tier        // Tiers set by WithTiers, highest first.
+	tierAt       int           // Number of tiers currently applying.
+	halved       bool          // If a Tier currently halves the capacity.
+	warnAt       int32         // Soft level set by WithWarnThreshold.
+	warnFunc     func(int32)   // Callback for when the remaining points dip below warnAt, nil if disabled.
+	warned       bool          // If the remaining points are currently below warnAt.
 
-	PauseFunc    func(int32, time.Duration) // Optional callback for when pause happens.
-	ResumeFunc   func()                     // Optional callback for when resume happens.
-	PauseBuffer  time.Duration              // Buffer of time to wait before attempting to re-aquire a spot.
-	AquireBuffer time.Duration              // Buffer of time to extend the pause with.
+	bulkBudget int32           // Cost past which a Bulk Operation is advised, 0 if disabled.
+	bulkFunc   func(BulkError) // Callback for when a Bulk Operation is advised.
 
-	pausedAt time.Time     // When paused last happened.
-	sema     chan struct{} // Semaphore for controlling the number of Goroutines running.
+	startedAt    time.Time   // When the Semaphore was created.
+	resumedAt    time.Time   // When the last pause was resumed from.
+	lastGrant    time.Time   // When a spot was last granted.
+	regrantTimer *time.Timer // Pending scheduled grant, nil if none.
 
-	mu     sync.Mutex // For handling paused flag control.
-	paused bool       // Pause flag.
+	BurstFactor float64 // Multiplier of cap allowed while points are plentiful, 0 for no bursting.
+	BurstAbove  float64 // Fraction of Limit which Remaining must be at or above to burst.
+
+	OnAcquire func(AcquireResult) // Optional hook for when a spot is acquired.
+	OnRelease func(ReleaseInfo)   // Optional hook for when a spot is released.
+
+	HookErrorHandler func(error)     // Optional callback for when a hook or callback panics.
+	CircuitOpenFunc  func(PauseInfo) // Optional callback for when the circuit breaker opens.
+
+	LeaseTTL         time.Duration // Duration before a Lease is automatically released, 0 for never.
+	LeaseExpiredFunc func(*Lease)  // Optional callback for when a Lease is automatically released.
+
+	pausedAt     time.Time      // When paused last happened.
+	pauseEnds    time.Time      // When the last pause is due to end.
+	pausePts     int32          // Point balance remaining when the last pause happened.
+	pauseReason  PauseReason    // Why the last pause happened.
+	holdUntil    time.Time      // When the current pause can resume, regardless of the point balance.
+	approved     bool           // If BeforeResume has approved resuming from the current pause.
+	pauseHist    []PauseRecord  // Last pauses, set by WithPauseHistory.
+	pauseHistN   int            // Number of pauses recorded into pauseHist.
+	cap          int            // Capacity of how many Goroutines can run at a time, 0 or less for no cap.
+	inflight     int            // Number of Goroutines currently holding a spot.
+	leased       int            // Number of spots, within inflight, held by a Lease.
+	pauses       int            // Number of pauses which have happened.
+	pausedFor    time.Duration  // Cumulative duration of completed pauses.
+	mark         statsMark      // Pause totals as of the last Mark.
+	acquisitions int            // Number of spots which have been granted.
+	classes      map[string]int // Number of spots held by each partition class.
+	failures     int            // Number of releases which were for failed requests.
+	lastErr      error          // Error from the last failed request.
+	queue        waitQueue      // Goroutines waiting for a spot, in order.
+
+	parent *Semaphore // Parent semaphore for a child, nil otherwise.
+
+	ctx context.Context // Lifecycle of the semaphore, cancelling it cancels all waiters.
+	err error           // Error from the lifecycle context once it is done.
+
+	mu     sync.Mutex    // For handling paused flag, spot and queue control.
+	paused bool          // Pause flag.
+	kick   chan struct{} // Signals the coordinator the pause has changed, nil while it is not running.
+
+	resumeCh chan struct{} // Closed on the next resume, nil until requested by ResumeCh.
 }
 
 // NewSemaphore returns a pointer to Semaphore. It accepts a cap which represents the
 // capacity of how many Goroutines can run at a time, it also accepts information
-// about the point balance and lastly, optional parameters.
+// about the point balance and lastly, optional parameters. A cap of 0 or less
+// means there is no cap on concurrency, only pausing based on points applies.
 func NewSemaphore(cap int, b *Balance, opts ...func(*Semaphore)) *Semaphore {
+	return NewSemaphoreWithContext(context.Background(), cap, b, opts...)
+}
+
+// NewSemaphoreWithContext returns a pointer to Semaphore in the same way as
+// NewSemaphore, but bound to the lifecycle of ctx. Once ctx is done, every
+// Goroutine waiting in Acquire, and any further calls to Acquire, will
+// return ctx.Err(). Any pending resume from a pause is abandoned, the pause
+// flag is cleared but the resume callbacks will not be called.
+func NewSemaphoreWithContext(ctx context.Context, cap int, b *Balance, opts ...func(*Semaphore)) *Semaphore {
+	var m BalanceModel
+	if b != nil {
+		m = b
+	}
+	return newSemaphoreFrom(ctx, cap, m, opts...)
+}
+
+// newSemaphoreFrom handles creating a Semaphore for the constructors, bound to the
+// lifecycle of ctx and consuming the model (m). If the model is a Balance, it
+// is also set as the Semaphore's Balance.
+func newSemaphoreFrom(ctx context.Context, cap int, m BalanceModel, opts ...func(*Semaphore)) *Semaphore {
+	b, _ := m.(*Balance)
+	now := time.Now()
 	sem := &Semaphore{
-		Balance: b,
-		sema:    make(chan struct{}, cap),
+		Balance:   b,
+		model:     m,
+		cap:       cap,
+		ctx:       ctx,
+		classes:   make(map[string]int),
+		startedAt: now,
+		mark:      statsMark{at: now},
 	}
 	for _, opt := range opts {
 		opt(sem)
@@ -48,44 +245,286 @@ func NewSemaphore(cap int, b *Balance, opts ...func(*Semaphore)) *Semaphore {
 		// Provide default PauseFunc.
 		WithPauseFunc(func(_ int32, _ time.Duration) {})(sem)
 	}
+	if sem.PauseInfoFunc == nil {
+		// Provide default PauseInfoFunc.
+		WithPauseInfoFunc(func(_ PauseInfo) {})(sem)
+	}
+	if sem.PauseFuncCtx == nil {
+		// Provide default PauseFuncCtx.
+		WithPauseFuncCtx(func(_ context.Context, _ PauseInfo) {})(sem)
+	}
 	if sem.ResumeFunc == nil {
 		// Provide default ResumeFunc.
 		WithResumeFunc(func() {})(sem)
 	}
-	if sem.AquireBuffer == 0 {
-		WithAquireBuffer(DefaultAquireBuffer)(sem)
+	if sem.ResumeInfoFunc == nil {
+		// Provide default ResumeInfoFunc.
+		WithResumeFuncInfo(func(_ ResumeInfo) {})(sem)
+	}
+	if sem.ResumeFuncCtx == nil {
+		// Provide default ResumeFuncCtx.
+		WithResumeFuncCtx(func(_ context.Context) {})(sem)
+	}
+	if sem.HookErrorHandler == nil {
+		// Provide default HookErrorHandler.
+		WithHookErrorHandler(func(_ error) {})(sem)
+	}
+	if sem.CircuitOpenFunc == nil {
+		// Provide default CircuitOpenFunc.
+		WithCircuitOpenFunc(func(_ PauseInfo) {})(sem)
+	}
+	if sem.OnAcquire == nil {
+		// Provide default OnAcquire.
+		WithOnAcquire(func(_ AcquireResult) {})(sem)
+	}
+	if sem.OnRelease == nil {
+		// Provide default OnRelease.
+		WithOnRelease(func(_ ReleaseInfo) {})(sem)
+	}
+	if sem.LeaseExpiredFunc == nil {
+		// Provide default LeaseExpiredFunc.
+		WithLeaseExpiredFunc(func(_ *Lease) {})(sem)
+	}
+	if sem.PauseStrategy == nil {
+		// Provide default PauseStrategy.
+		WithPauseStrategy(RefillStrategy{})(sem)
+	}
+	if sem.AcquireBuffer == 0 {
+		WithAcquireBuffer(DefaultAcquireBuffer)(sem)
+	}
+	if ctx.Done() != nil {
+		go sem.watch()
 	}
 	return sem
 }
 
-// Aquire will attempt to aquire a spot to run the Goroutine.
-// It will continue in a loop until it does aquire also pausing
-// if the pause flag has been enabled. Aquiring is throttled at
-// the value of AquireBuffer.
-func (sem *Semaphore) Aquire(ctx context.Context) (err error) {
-	for aquired := false; !aquired; {
-		for {
-			if !sem.paused {
-				// Not paused. Break loop.
-				break
+// NewSemaphoreE returns a pointer to Semaphore in the same way as NewSemaphore,
+// but will first validate the configuration, returning an error if it would
+// not work. Such as a nil Balance, or a zero RefillRate which would otherwise
+// cause a divide-by-zero in RefillDuration.
+func NewSemaphoreE(cap int, b *Balance, opts ...func(*Semaphore)) (*Semaphore, error) {
+	if b == nil {
+		return nil, ErrNilBalance
+	}
+	if b.RefillRate <= 0 {
+		return nil, fmt.Errorf("%w: got %v", ErrInvalidRefillRate, b.RefillRate)
+	}
+	return NewSemaphore(cap, b, opts...), nil
+}
+
+// watch will wait for the lifecycle context to be done and then cancel
+// every waiting Goroutine with the context's error.
+func (sem *Semaphore) watch() {
+	<-sem.ctx.Done()
+
+	defer sem.mu.Unlock()
+	sem.mu.Lock()
+	sem.err = sem.ctx.Err()
+	sem.cancelWaiters(sem.err)
+}
+
+// Acquire will attempt to acquire a spot to run the Goroutine with
+// PriorityNormal. See AcquireWithPriority.
+func (sem *Semaphore) Acquire(ctx context.Context) error {
+	return sem.AcquireWithPriority(ctx, PriorityNormal)
+}
+
+// Aquire will attempt to acquire a spot to run the Goroutine.
+//
+// Deprecated: use Acquire.
+func (sem *Semaphore) Aquire(ctx context.Context) error {
+	return sem.Acquire(ctx)
+}
+
+// AcquireWithPriority will attempt to acquire a spot to run the Goroutine.
+// If a spot is available and no other Goroutines are waiting, it is
+// acquired immediately. Otherwise, the Goroutine will join the back of
+// the queue for its priority and wait until it is granted a spot, pausing
+// if the pause flag has been enabled, or until the context is cancelled,
+// including part way through a pause.
+// Waiters of a higher priority are granted spots before all waiters of a
+// lower priority. If MaxWaiters is set and that many Goroutines are already
+// waiting, ErrQueueFull is returned without waiting.
+func (sem *Semaphore) AcquireWithPriority(ctx context.Context, prio Priority) error {
+	_, err := sem.acquire(ctx, prio, "", false)
+	return err
+}
+
+// AcquireKeyed will attempt to acquire a spot to run the Goroutine with
+// PriorityNormal on behalf of key, such as a tenant or job type. Waiting
+// keys take turns in being granted a spot, so a key with many waiters
+// can not starve other keys sharing the semaphore. See AcquireWithPriority.
+func (sem *Semaphore) AcquireKeyed(ctx context.Context, key string) error {
+	_, err := sem.acquire(ctx, PriorityNormal, key, false)
+	return err
+}
+
+// AcquireResult represents information about how a spot was acquired,
+// which can be attached to request logging.
+type AcquireResult struct {
+	Waited    time.Duration // How long was spent waiting for the spot.
+	Pauses    int           // Number of pause cycles sat through while waiting.
+	Remaining int32         // Point balance remaining when the spot was granted.
+}
+
+// AcquireInfo will attempt to acquire a spot to run the Goroutine with
+// PriorityNormal, in the same way as Acquire, returning information about
+// the wait. See AcquireWithPriority.
+func (sem *Semaphore) AcquireInfo(ctx context.Context) (AcquireResult, error) {
+	return sem.acquire(ctx, PriorityNormal, "", false)
+}
+
+// acquire handles acquiring a spot for the Acquire methods, running the
+// OnAcquire hook once acquired. If class is true, the key is the partition
+// class the spot is counted against.
+func (sem *Semaphore) acquire(ctx context.Context, prio Priority, key string, class bool) (AcquireResult, error) {
+	res, err := sem.wait(ctx, prio, key, class)
+	if err == nil && sem.parent != nil {
+		// Child semaphore, also requires a spot from the parent.
+		var pres AcquireResult
+		if pres, err = sem.parent.acquire(ctx, prio, key, false); err != nil {
+			sem.mu.Lock()
+			sem.inflight -= 1
+			if class {
+				sem.classes[key] -= 1
 			}
+			sem.grant()
+			sem.mu.Unlock()
+			return AcquireResult{}, err
 		}
+		res.Waited += pres.Waited
+		res.Pauses += pres.Pauses
+		res.Remaining = pres.Remaining
+	}
+	if err == nil {
+		sem.mu.Lock()
+		fn := sem.OnAcquire
+		sem.mu.Unlock()
+		sem.hook("OnAcquire", func() { fn(res) })
+	}
+	return res, err
+}
+
+// wait handles waiting for a spot to be granted for acquire.
+func (sem *Semaphore) wait(ctx context.Context, prio Priority, key string, class bool) (AcquireResult, error) {
+	var cls string
+	if class {
+		cls = key
+	}
+
+	start := time.Now()
+	result := func(pauses int) AcquireResult {
+		return AcquireResult{
+			Waited:    time.Since(start),
+			Pauses:    pauses,
+			Remaining: sem.remaining(),
+		}
+	}
+
+	sem.mu.Lock()
+	if sem.err != nil {
+		// Semaphore's lifecycle has ended.
+		sem.mu.Unlock()
+		return AcquireResult{}, sem.err
+	}
+	if sem.available() && sem.queue.len() == 0 {
+		// Spot available and nobody ahead of us.
+		sem.take(cls)
+		sem.mu.Unlock()
+		return result(0), nil
+	}
+	if sem.MaxWaiters > 0 && sem.queue.len() >= sem.MaxWaiters {
+		sem.mu.Unlock()
+		return AcquireResult{}, ErrQueueFull
+	}
+	if dl, ok := ctx.Deadline(); ok && sem.paused && dl.Before(sem.pauseEnds) {
+		// Pause will outlast the caller.
+		err := sem.pausedErr(ErrDeadlineWouldExceed)
+		sem.mu.Unlock()
+		return AcquireResult{}, err
+	}
+	if mw := sem.MaxWait; mw > 0 {
+		if proj := sem.projected(); proj > mw {
+			// Known to wait longer than allowed.
+			sem.mu.Unlock()
+			return AcquireResult{}, &MaxWaitError{Wait: proj, MaxWait: mw}
+		}
+	}
+	w := sem.queue.push(prio, key)
+	w.class = cls
+	sem.grant() // Schedule a regrant if held back by pacing or warming up.
+	pauses := sem.pauses
+	if sem.paused {
+		// Count the pause currently in progress.
+		pauses -= 1
+	}
+	var expired <-chan time.Time
+	if mw := sem.MaxWait; mw > 0 {
+		t := time.NewTimer(mw)
+		defer t.Stop()
+		expired = t.C
+	}
+	sem.mu.Unlock()
 
-		// Attempt to aquire a spot, if not we will throttle the next loop.
+	// leave will leave the queue, or if a spot was granted in the
+	// meantime, hand it back for the next waiter.
+	leave := func() {
+		defer sem.mu.Unlock()
+		sem.mu.Lock()
 		select {
-		case <-ctx.Done():
-			// Context cancelled. Break loop and return error.
-			aquired = true
-			err = ctx.Err()
-		case sem.sema <- struct{}{}:
-			// Spot aquired. Break loop.
-			aquired = true
+		case <-w.ready:
+			if w.err == nil {
+				sem.inflight -= 1
+				if cls != "" {
+					sem.classes[cls] -= 1
+				}
+				sem.grant()
+			}
 		default:
-			// Can not yet aquire a spot. Throttle for a set duration.
-			time.Sleep(sem.AquireBuffer)
+			sem.queue.remove(w)
 		}
 	}
-	return
+
+	select {
+	case <-w.ready:
+		if w.err != nil {
+			// Cancelled while waiting.
+			return AcquireResult{}, w.err
+		}
+		// Spot granted.
+		return result(w.pauses - pauses), nil
+	case <-ctx.Done():
+		// Context cancelled.
+		leave()
+		sem.mu.Lock()
+		err := sem.pausedErr(ctx.Err())
+		sem.mu.Unlock()
+		return AcquireResult{}, err
+	case <-expired:
+		// Waited as long as allowed.
+		leave()
+		sem.mu.Lock()
+		err := &MaxWaitError{Wait: time.Since(start) + sem.projected(), MaxWait: sem.MaxWait}
+		sem.mu.Unlock()
+		return AcquireResult{}, err
+	}
+}
+
+// projected returns the projected duration until a new waiter would be
+// granted a spot, based on what passes with time, such as the remainder
+// of a pause in progress and pacing for the waiters ahead of it. Waiting
+// on spots to be released can not be projected and is not included.
+// It must be called while holding mu.
+func (sem *Semaphore) projected() time.Duration {
+	var wait time.Duration
+	if sem.paused {
+		wait = time.Until(sem.pauseEnds)
+	}
+	wait += sem.retryIn()
+	if iv := sem.interval(); iv > 0 {
+		wait += iv * time.Duration(sem.queue.len())
+	}
+	return max(wait, 0)
 }
 
 // Release will release a spot for another Goroutine to take.
@@ -93,33 +532,464 @@ func (sem *Semaphore) Aquire(ctx context.Context) (err error) {
 // remaining point balance will only be updated if the count is greater than -1.
 // If the remaining points is below the set threshold, a pause will be
 // initiated and a duration of this pause will be calculated based
-// upon several factors surrouding the point information such as limit,
-// threshold, and the refull rate.
+// upon several factors surrounding the point information such as limit,
+// threshold, and the refill rate. It will panic if no spot is held, as
+// releasing without a matching acquire is a programming error.
 func (sem *Semaphore) Release(pts int32) {
+	sem.release(pts, nil, false)
+}
+
+// ReleaseWithErr will release a spot for another Goroutine to take, for when
+// the request failed and no point information was returned, such as from a
+// network error. The point balance is not updated, as if ErrPts was passed
+// to Release, and the failure is recorded against the Semaphore's stats.
+func (sem *Semaphore) ReleaseWithErr(err error) {
+	sem.release(ErrPts, err, false)
+}
+
+// ReleaseInfo represents information about a released spot, passed
+// to the OnRelease hook.
+type ReleaseInfo struct {
+	Before int32 // Point balance remaining before the release.
+	After  int32 // Point balance remaining after the release.
+	Delta  int32 // Change in point balance, negative when points were consumed.
+	Err    error // Error the spot was released with, nil if successful.
+}
+
+// release handles releasing a spot for Release, ReleaseWithErr, and Lease,
+// running the OnRelease hook once released. Leased should be true if the
+// spot is held by a Lease.
+func (sem *Semaphore) release(pts int32, err error, leased bool) {
+	if sem.parent != nil {
+		// Child semaphore, the parent owns the point balance and pausing.
+		sem.releaseChild(err, leased)
+		sem.parent.release(pts, err, false)
+		return
+	}
+	info, fn := sem.releaseSpot(pts, err, leased)
+	sem.hook("OnRelease", func() { fn(info) })
+}
+
+// releaseSpot handles the actual release for release, returning
+// information about the release and the OnRelease hook to run.
+func (sem *Semaphore) releaseSpot(pts int32, err error, leased bool) (ReleaseInfo, func(ReleaseInfo)) {
 	defer sem.mu.Unlock()
 	sem.mu.Lock()
 
-	sem.Update(pts)
-	if sem.AtThreshold() {
-		// Calculate the duration required to refill and that duration time
-		// has passed before we call for a pause.
-		ra := sem.RefillDuration() + sem.PauseBuffer
-		if sem.pausedAt.Add(ra).Before(time.Now()) {
-			sem.paused = true
-			sem.pausedAt = time.Now()
-			go sem.PauseFunc(pts, ra)
-
-			// Unflag as paused after the determined duration and run the ResumeFunc.
-			go func() {
-				time.Sleep(ra)
-				sem.paused = false
-				sem.ResumeFunc()
-			}()
+	sem.checkHeld(leased)
+	sem.record(err)
+	info := ReleaseInfo{Before: sem.remaining(), Err: err}
+
+	sem.model.Update(pts)
+	if sem.paused && sem.recovered() && !sem.held(time.Now()) {
+		// Recovered well above the threshold, stop waiting out the pause.
+		sem.resumeEarly()
+	}
+	sem.evalWarn()
+	tierPause := sem.evalTiers(pts)
+	ra, ok := sem.PauseStrategy.Pause(sem.pauseModel(), sem.PauseBuffer)
+	if !ok && tierPause {
+		// A Tier calls for a pause, refill back to full as for the threshold.
+		ra, ok = sem.model.RefillDuration()+sem.PauseBuffer, true
+	}
+	paused := ok && sem.pausedAt.Add(ra).Before(time.Now())
+	if paused {
+		// Pause if that duration of time has passed since the last pause.
+		reason := PauseThresholdReached
+		if errors.Is(err, ErrThrottled) {
+			reason = PauseThrottled
+		}
+		dur := sem.decide(pts, sem.jitter(ra), reason)
+		if sem.MaxPause > 0 {
+			dur = min(dur, sem.MaxPause)
+		}
+		sem.pause(pts, dur, reason)
+	}
+	sem.evalCircuit(pts, paused, errors.Is(err, ErrThrottled))
+	if sem.adaptive != nil {
+		if errors.Is(err, ErrThrottled) {
+			sem.adaptive.decrease()
+		} else if err == nil && !sem.paused {
+			sem.adaptive.increase()
 		}
 	}
 
 	// Perform the actual release.
-	<-sem.sema
+	sem.free(leased)
+
+	info.After = sem.remaining()
+	info.Delta = info.After - info.Before
+	return info, sem.OnRelease
+}
+
+// pause will flag the Semaphore as paused for the duration (dur), running
+// the PauseFunc and PauseInfoFunc with the reason, and then resume once the
+// duration has passed. It must be called while holding mu.
+func (sem *Semaphore) pause(pts int32, dur time.Duration, reason PauseReason) {
+	info := sem.pauseInfo(pts, dur, reason)
+	sem.paused = true
+	sem.pausedAt = info.At
+	sem.pauseEnds = sem.pausedAt.Add(dur)
+	sem.pausePts = info.Remaining
+	sem.pauseReason = reason
+	sem.approved = false
+	sem.pauses += 1
+	sem.recordPause(info)
+	if sem.adaptive != nil {
+		sem.adaptive.decrease()
+	}
+	fn, fnInfo, fnCtx, ctx := sem.PauseFunc, sem.PauseInfoFunc, sem.PauseFuncCtx, sem.ctx
+	go sem.hook("PauseFunc", func() { fn(pts, dur) })
+	go sem.hook("PauseInfoFunc", func() { fnInfo(info) })
+	go sem.hook("PauseFuncCtx", func() { fnCtx(ctx, info) })
+
+	// Have the coordinator unflag as paused once the pause is over,
+	// grant any waiters their spots, and run the resume callbacks.
+	sem.coordinate()
+}
+
+// hold will pause for the duration (dur) for the reason, at the point balance
+// (pts), without resuming early even if the point balance recovers. If
+// already paused, the current pause is held until at least the duration has
+// passed instead. It must be called while holding mu.
+func (sem *Semaphore) hold(pts int32, dur time.Duration, reason PauseReason) {
+	until := time.Now().Add(dur)
+	if !sem.paused {
+		sem.pause(pts, dur, reason)
+	} else if until.After(sem.pauseEnds) {
+		sem.pauseEnds = until
+		sem.pauseReason = reason
+		sem.approved = false
+	}
+	if until.After(sem.holdUntil) {
+		sem.holdUntil = until
+	}
+	sem.coordinate()
+}
+
+// held returns true if the current pause is held until a later time
+// than now. It must be called while holding mu.
+func (sem *Semaphore) held(now time.Time) bool {
+	return now.Before(sem.holdUntil)
+}
+
+// onResume returns a func which will run the resume callbacks with the
+// details of the pause (info), so they can be run once mu is released.
+// It must be called while holding mu.
+func (sem *Semaphore) onResume(info ResumeInfo) func() {
+	fn, fnInfo, fnCtx, ctx := sem.ResumeFunc, sem.ResumeInfoFunc, sem.ResumeFuncCtx, sem.ctx
+	return func() {
+		sem.hook("ResumeFunc", fn)
+		sem.hook("ResumeInfoFunc", func() { fnInfo(info) })
+		sem.hook("ResumeFuncCtx", func() { fnCtx(ctx) })
+	}
+}
+
+// cleared returns true if the point balance has cleared the threshold, or
+// recovered to ResumeAbove if set, for a pause with a PauseTick.
+// It must be called while holding mu.
+func (sem *Semaphore) cleared() bool {
+	if sem.ResumeAbove > 0 {
+		return sem.recovered()
+	}
+	return !sem.pauseModel().AtThreshold()
+}
+
+// recovered returns true if the remaining points have climbed to the
+// ResumeAbove fraction of the limit, such as when another process stopped
+// consuming. It must be called while holding mu.
+func (sem *Semaphore) recovered() bool {
+	if sem.ResumeAbove <= 0 {
+		return false
+	}
+	return float64(sem.model.Current()) >= sem.ResumeAbove*float64(sem.model.Max())
+}
+
+// resumeEarly will resume from the current pause without waiting out the
+// rest of its duration, granting any waiters their spots and running the
+// resume callbacks. It must be called while holding mu.
+func (sem *Semaphore) resumeEarly() {
+	info := sem.unpause()
+	sem.grant()
+	go sem.onResume(info)()
+	sem.coordinate()
+}
+
+// jitter returns the pause duration (dur) randomly extended by up to the
+// PauseJitter fraction of it. It is only ever extended, as resuming early
+// would risk being throttled. It must be called while holding mu.
+func (sem *Semaphore) jitter(dur time.Duration) time.Duration {
+	if sem.PauseJitter <= 0 {
+		return dur
+	}
+	return dur + time.Duration(rand.Float64()*sem.PauseJitter*float64(dur))
+}
+
+// unpause will unflag the Semaphore as paused, recording how long it
+// was paused for, and return the details of the pause for the resume
+// callbacks. It must be called while holding mu.
+func (sem *Semaphore) unpause() ResumeInfo {
+	sem.resumedAt = time.Now()
+	dur := sem.resumedAt.Sub(sem.pausedAt)
+	sem.paused = false
+	sem.pausedFor += dur
+	sem.recordResume(dur)
+	if sem.resumeCh != nil {
+		// Broadcast the resume to everyone waiting on ResumeCh.
+		close(sem.resumeCh)
+		sem.resumeCh = nil
+	}
+	return ResumeInfo{
+		Reason:   sem.pauseReason,
+		Duration: dur,
+		Before:   sem.pausePts,
+		After:    sem.remaining(),
+		Waiters:  sem.queue.len(),
+		Shop:     sem.Shop,
+	}
+}
+
+// checkHeld will panic if there is no spot held to release, as releasing
+// without a matching acquire would allow more than cap Goroutines to run.
+// Spots held by a Lease can only be released through the Lease.
+// It must be called while holding mu.
+func (sem *Semaphore) checkHeld(leased bool) {
+	held := sem.inflight - sem.leased
+	if leased {
+		held = sem.leased
+	}
+	if held <= 0 {
+		panic("shopifysemaphore: release of unheld spot")
+	}
+}
+
+// record will record a failed request against the stats, if err is set.
+// It must be called while holding mu.
+func (sem *Semaphore) record(err error) {
+	if err != nil {
+		sem.failures += 1
+		sem.lastErr = err
+	}
+}
+
+// free will give up a held spot and grant it to the next waiter.
+// It must be called while holding mu.
+func (sem *Semaphore) free(leased bool) {
+	if leased {
+		sem.leased -= 1
+	}
+	sem.inflight -= 1
+	sem.grant()
+}
+
+// available returns true if a spot can be granted right now.
+// It must be called while holding mu.
+func (sem *Semaphore) available() bool {
+	c := sem.capacity()
+	return !sem.paused && (c <= 0 || sem.inflight < c) && sem.paced() == 0
+}
+
+// paced returns how long until the next spot can be granted due to the
+// MinInterval or SoftThrottle, 0 if it can be granted now.
+// It must be called while holding mu.
+func (sem *Semaphore) paced() time.Duration {
+	iv := sem.interval()
+	if iv <= 0 || sem.lastGrant.IsZero() {
+		return 0
+	}
+	if wait := iv - time.Since(sem.lastGrant); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// next will remove and return the next waiter to be granted a spot. With
+// spot shares, waiters whose class is within its share are granted first,
+// otherwise a waiter may borrow the unused share of other classes.
+// It must be called while holding mu.
+func (sem *Semaphore) next() *waiter {
+	if len(sem.SpotShares) == 0 {
+		return sem.queue.pop()
+	}
+	return sem.queue.popPrefer(func(w *waiter) bool {
+		return w.class == "" || sem.classes[w.class] < sem.share(w.class)
+	})
+}
+
+// share returns the number of spots the partition class is entitled
+// to without borrowing. It must be called while holding mu.
+func (sem *Semaphore) share(class string) int {
+	c := sem.capacity()
+	if c <= 0 {
+		return math.MaxInt
+	}
+	return int(math.Ceil(float64(c) * sem.SpotShares[class]))
+}
+
+// take will mark a spot as held, counting it against the partition class
+// if set. It must be called while holding mu.
+func (sem *Semaphore) take(class string) {
+	sem.inflight += 1
+	sem.acquisitions += 1
+	if class != "" {
+		sem.classes[class] += 1
+	}
+	sem.lastGrant = time.Now()
+	sem.signal()
+}
+
+// regrant will schedule grant to run again after the duration (dur), for
+// when waiters are held back by something which passes with time, such as
+// the MinInterval or Warmup. Only one regrant is scheduled at a time, as every grant
+// will schedule another if still required. It must be called while holding mu.
+func (sem *Semaphore) regrant(dur time.Duration) {
+	if sem.regrantTimer != nil {
+		return
+	}
+	sem.regrantTimer = time.AfterFunc(dur, func() {
+		defer sem.mu.Unlock()
+		sem.mu.Lock()
+		sem.regrantTimer = nil
+		sem.grant()
+	})
+}
+
+// capacity returns how many Goroutines can currently run at a time. This is
+// the cap, or the adaptive limit if enabled, scaled by the BurstFactor while
+// the remaining points are at or above BurstAbove of the Limit, halved
+// by a Tier, reduced by a Window, and ramped up during the Warmup and the
+// Cooldown after a pause.
+// It must be called while holding mu.
+func (sem *Semaphore) capacity() int {
+	c := sem.cap
+	if sem.adaptive != nil {
+		c = sem.adaptive.limit
+	}
+	if c > 0 && sem.BurstFactor > 1 {
+		above := float64(sem.model.Max()) * sem.BurstAbove
+		if float64(sem.model.Current()) >= above {
+			c = int(float64(c) * sem.BurstFactor)
+		}
+	}
+	if c > 1 && sem.halved {
+		c /= 2
+	}
+	if w, _, ok := sem.window(time.Now()); ok && c > 1 && w.Capacity > 0 {
+		c = max(1, int(float64(c)*w.Capacity))
+	}
+	if sem.Warmup > 0 {
+		c, _ = ramp(c, sem.startedAt, sem.Warmup)
+	}
+	if sem.Cooldown > 0 && !sem.resumedAt.IsZero() {
+		c, _ = ramp(c, sem.resumedAt, sem.Cooldown)
+	}
+	return c
+}
+
+// retryIn returns how long until waiters, held back by something which
+// passes with time such as pacing, warming up, or cooling down, may be granted a spot.
+// It returns 0 if nothing time based is holding them back. It must be
+// called while holding mu.
+func (sem *Semaphore) retryIn() time.Duration {
+	wait := sem.paced()
+	if sem.Warmup > 0 {
+		if _, step := ramp(sem.cap, sem.startedAt, sem.Warmup); step > 0 && (wait == 0 || step < wait) {
+			wait = step
+		}
+	}
+	if sem.Cooldown > 0 && !sem.resumedAt.IsZero() {
+		if _, step := ramp(sem.cap, sem.resumedAt, sem.Cooldown); step > 0 && (wait == 0 || step < wait) {
+			wait = step
+		}
+	}
+	if w, end, ok := sem.window(time.Now()); ok && w.Capacity > 0 {
+		if step := time.Until(end); step > 0 && (wait == 0 || step < wait) {
+			wait = step
+		}
+	}
+	return wait
+}
+
+// grant will hand out spots to waiters at the front of the queue for as
+// long as spots are available, scheduling itself to run again if waiters
+// are held back by pacing or warming up. It must be called while holding mu.
+func (sem *Semaphore) grant() {
+	for sem.available() {
+		w := sem.next()
+		if w == nil {
+			break
+		}
+		sem.take(w.class)
+		w.pauses = sem.pauses
+		close(w.ready)
+	}
+	sem.signal()
+	if wait := sem.retryIn(); wait > 0 && sem.queue.len() > 0 {
+		// Waiters are held back by pacing or warming up, try again later.
+		sem.regrant(wait)
+	}
+}
+
+// CancelWaiters will wake every Goroutine currently waiting in Acquire with
+// err instead of a spot, such as when a shop uninstalls the app and queued
+// work should be abandoned. Spots already held are not affected, and the
+// Semaphore can continue to be used afterwards.
+func (sem *Semaphore) CancelWaiters(err error) {
+	defer sem.mu.Unlock()
+	sem.mu.Lock()
+	sem.cancelWaiters(err)
+}
+
+// cancelWaiters will remove every waiter from the queue, waking them with
+// err instead of granting a spot. It must be called while holding mu.
+func (sem *Semaphore) cancelWaiters(err error) {
+	for w := sem.queue.pop(); w != nil; w = sem.queue.pop() {
+		w.err = err
+		close(w.ready)
+	}
+}
+
+// SetPauseFunc will safely replace the PauseFunc while the Semaphore is in use.
+// Writing the PauseFunc field directly once the Semaphore is in use is racy.
+func (sem *Semaphore) SetPauseFunc(fn func(int32, time.Duration)) {
+	defer sem.mu.Unlock()
+	sem.mu.Lock()
+	WithPauseFunc(fn)(sem)
+}
+
+// SetResumeFunc will safely replace the ResumeFunc while the Semaphore is in use.
+// Writing the ResumeFunc field directly once the Semaphore is in use is racy.
+func (sem *Semaphore) SetResumeFunc(fn func()) {
+	defer sem.mu.Unlock()
+	sem.mu.Lock()
+	WithResumeFunc(fn)(sem)
+}
+
+// SetPauseBuffer will safely replace the PauseBuffer while the Semaphore is
+// in use. It will apply to the next pause. Writing the PauseBuffer field
+// directly once the Semaphore is in use is racy.
+func (sem *Semaphore) SetPauseBuffer(dur time.Duration) {
+	defer sem.mu.Unlock()
+	sem.mu.Lock()
+	WithPauseBuffer(dur)(sem)
+}
+
+// SetAcquireBuffer will safely replace the AcquireBuffer while the Semaphore
+// is in use, matching WithAcquireBuffer. AcquireBuffer is currently unused,
+// as spots are granted directly to waiters.
+func (sem *Semaphore) SetAcquireBuffer(dur time.Duration) {
+	defer sem.mu.Unlock()
+	sem.mu.Lock()
+	WithAcquireBuffer(dur)(sem)
+}
+
+// SetMaxWaiters will safely replace the MaxWaiters while the Semaphore is
+// in use. Goroutines already waiting are not affected. Writing the MaxWaiters
+// field directly once the Semaphore is in use is racy.
+func (sem *Semaphore) SetMaxWaiters(n int) {
+	defer sem.mu.Unlock()
+	sem.mu.Lock()
+	WithMaxWaiters(n)(sem)
 }
 
 // withPauseFunc is a functional option for Semaphore to call when
@@ -139,11 +1009,31 @@ func WithResumeFunc(fn func()) func(*Semaphore) {
 	}
 }
 
+// WithAcquireBuffer is a functional option for Semaphore which
+// will set the throttle duration for attempting to re-acquire a spot.
+// AcquireBuffer is currently unused, as spots are granted directly
+// to waiters.
+func WithAcquireBuffer(dur time.Duration) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.AcquireBuffer = dur
+		sem.AquireBuffer = dur
+	}
+}
+
 // WithAquireBuffer is a functional option for Semaphore which
-// will set the throttle duration for attempting to re-aquire a spot.
+// will set the throttle duration for attempting to re-acquire a spot.
+//
+// Deprecated: use WithAcquireBuffer.
 func WithAquireBuffer(dur time.Duration) func(*Semaphore) {
+	return WithAcquireBuffer(dur)
+}
+
+// WithMaxWaiters is a functional option for Semaphore which will
+// set the maximum number of Goroutines which can be waiting to acquire
+// a spot at once. Once reached, Acquire will return ErrQueueFull.
+func WithMaxWaiters(n int) func(*Semaphore) {
 	return func(sem *Semaphore) {
-		sem.AquireBuffer = dur
+		sem.MaxWaiters = n
 	}
 }
 
@@ -154,3 +1044,158 @@ func WithPauseBuffer(dur time.Duration) func(*Semaphore) {
 		sem.PauseBuffer = dur
 	}
 }
+
+// WithLeaseTTL is a functional option for Semaphore which will set
+// the duration a Lease can be held before it is automatically released.
+func WithLeaseTTL(dur time.Duration) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.LeaseTTL = dur
+	}
+}
+
+// WithLeaseExpiredFunc is a functional option for Semaphore to call
+// when a Lease was not released within LeaseTTL and has been
+// automatically released. The expired Lease will be passed into
+// the function.
+func WithLeaseExpiredFunc(fn func(*Lease)) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.LeaseExpiredFunc = fn
+	}
+}
+
+// WithBurst is a functional option for Semaphore which will allow up to
+// factor times the cap of Goroutines to run while the remaining points are
+// at or above the fraction (above) of the Limit, such as 2x while above 80%.
+// Once the remaining points decay below, capacity shrinks back to the cap
+// as spots are released.
+func WithBurst(factor float64, above float64) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.BurstFactor = factor
+		sem.BurstAbove = above
+	}
+}
+
+// WithAdaptive is a functional option for Semaphore which will enable
+// adaptive concurrency. Starting from the cap, the number of Goroutines
+// which can run at a time is increased while requests succeed, and halved
+// whenever a pause happens or a request is released with ErrThrottled,
+// always staying between min and max.
+func WithAdaptive(min int, max int) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.adaptive = newAIMD(sem.cap, min, max)
+	}
+}
+
+// WithMinInterval is a functional option for Semaphore which will space
+// out spots being granted by at least the duration (dur), instead of every
+// free spot being granted at once. This smooths the drawdown of points.
+func WithMinInterval(dur time.Duration) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.MinInterval = dur
+	}
+}
+
+// WithOnAcquire is a functional option for Semaphore to call every time a
+// spot is acquired, with information about the wait. It runs on the acquiring
+// Goroutine before Acquire returns, so it should be quick.
+func WithOnAcquire(fn func(AcquireResult)) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.OnAcquire = fn
+	}
+}
+
+// WithOnRelease is a functional option for Semaphore to call every time a
+// spot is released, with information about the change in points. It runs on
+// the releasing Goroutine before Release returns, so it should be quick.
+func WithOnRelease(fn func(ReleaseInfo)) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.OnRelease = fn
+	}
+}
+
+// WithSpotShares is a functional option for Semaphore which will split the
+// spots between named classes of work by share, such as 0.6 for "sync", 0.3
+// for "webhooks", and 0.1 for "adhoc". Spots are acquired for a class with
+// AcquireClass. Waiters within their class's share are granted first, but a
+// class may borrow spots another class is not using. Only the spots are
+// split, not the point balance, so a class within its share may still
+// consume any of the points. With no capacity limit, every class is within
+// its share, so the shares have no effect.
+func WithSpotShares(shares map[string]float64) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.SpotShares = shares
+	}
+}
+
+// WithWarmup is a functional option for Semaphore which will start it at a
+// capacity of 1 spot, ramping up to the full capacity over the duration (dur).
+// This stops a freshly restarted worker slamming a point balance which may
+// already be depleted, before it has any information about it.
+func WithWarmup(dur time.Duration) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.Warmup = dur
+	}
+}
+
+// WithCooldown is a functional option for Semaphore which will ramp the
+// capacity back up from 1 spot to the full capacity over the duration (dur)
+// after each pause, instead of granting every waiter at once, which would
+// drain the barely refilled point balance and cause another pause.
+func WithCooldown(dur time.Duration) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.Cooldown = dur
+	}
+}
+
+// WithMaxWait is a functional option for Semaphore which will set the
+// maximum duration (dur) a Goroutine will wait in Acquire for a spot.
+// If the projected wait is already longer, or the duration passes without
+// a spot being granted, Acquire will return a MaxWaitError.
+func WithMaxWait(dur time.Duration) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.MaxWait = dur
+	}
+}
+
+// WithPauseJitter is a functional option for Semaphore which will randomly
+// extend each pause by up to the fraction (frac) of its duration, such as 0.1
+// for up to 10%. This stops many workers serving the same shop all resuming
+// at the same instant and exhausting the point balance again together.
+func WithPauseJitter(frac float64) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.PauseJitter = frac
+	}
+}
+
+// WithMaxPause is a functional option for Semaphore which will clamp every
+// pause to the duration (dur), including any jitter. This stops a corrupted
+// remaining point value or a wrong refill rate from stalling everything
+// with an absurdly long pause.
+func WithMaxPause(dur time.Duration) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.MaxPause = dur
+	}
+}
+
+// WithEarlyResume is a functional option for Semaphore which will resume early
+// from a pause once an update shows the remaining points have recovered to the
+// fraction (above) of the limit, such as 0.5 for 50%, instead of waiting out
+// the entire pause.
+func WithEarlyResume(above float64) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.ResumeAbove = above
+	}
+}
+
+// WithPauseTick is a functional option for Semaphore which will re-evaluate
+// the point balance, accounting for points refilled since the last update,
+// every tick (dur) during a pause instead of waiting out the computed duration.
+// The pause ends as soon as the threshold is cleared, or the ResumeAbove set
+// by WithEarlyResume is reached, and continues past the computed duration for
+// as long as it has not, such as when refilling is slower than expected, up
+// to any MaxPause.
+func WithPauseTick(dur time.Duration) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.PauseTick = dur
+	}
+}
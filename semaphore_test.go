@@ -12,10 +12,23 @@ func newSemaphore(cap int, opts ...func(*Semaphore)) *Semaphore {
 	return NewSemaphore(cap, NewBalance(900, 1000, 100), opts...)
 }
 
-// TestAquire should run N Goroutines. Allowing them
-// to aquire and release their spot. We are expecting no error to happen
+// waitFor will block until n Goroutines are waiting in the queue.
+func waitFor(sem *Semaphore, n int) {
+	for {
+		sem.mu.Lock()
+		l := sem.queue.len()
+		sem.mu.Unlock()
+		if l == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestAcquire should run N Goroutines. Allowing them
+// to acquire and release their spot. We are expecting no error to happen
 // and for the count (cnt) to match the number of Goroutines N.
-func TestAquire(t *testing.T) {
+func TestAcquire(t *testing.T) {
 	var err error
 	var wg sync.WaitGroup
 	var cnt int // Count of Gorotunes which ran.
@@ -28,7 +41,7 @@ func TestAquire(t *testing.T) {
 	for i := 0; i < n; i += 1 {
 		wg.Add(1)
 		go func() {
-			err = sema.Aquire(ctx)
+			err = sema.Acquire(ctx)
 			if err != nil {
 				wg.Done()
 				return
@@ -46,7 +59,7 @@ func TestAquire(t *testing.T) {
 		t.Errorf("cnt = %d; want %d", cnt, n)
 	}
 	if err != nil {
-		t.Errorf("Aquire(%q) = %v; want nil", ctx, err)
+		t.Errorf("Acquire(%q) = %v; want nil", ctx, err)
 	}
 }
 
@@ -75,7 +88,7 @@ func TestReleaseCausesPauseAndResume(t *testing.T) {
 	for i := 0; i < n; i += 1 {
 		wg.Add(1)
 		go func() {
-			err = sema.Aquire(ctx)
+			err = sema.Acquire(ctx)
 			if err != nil {
 				wg.Done()
 				return
@@ -100,9 +113,9 @@ func TestReleaseCausesPauseAndResume(t *testing.T) {
 	}
 }
 
-// TestAquireCtxErr should detect a context error on attempting
-// to aquire a spot.
-func TestAquireCtxErr(t *testing.T) {
+// TestAcquireCtxErr should detect a context error on attempting
+// to acquire a spot.
+func TestAcquireCtxErr(t *testing.T) {
 	var err error
 	var wg sync.WaitGroup
 
@@ -117,7 +130,7 @@ func TestAquireCtxErr(t *testing.T) {
 	for i := 0; i < n; i += 1 {
 		wg.Add(1)
 		go func() {
-			err = sema.Aquire(ctx)
+			err = sema.Acquire(ctx)
 			if err != nil {
 				wg.Done()
 				return
@@ -136,3 +149,782 @@ func TestAquireCtxErr(t *testing.T) {
 		t.Errorf("err = %v; want %v", err, context.DeadlineExceeded)
 	}
 }
+
+// TestAcquireQueueFull should reject attempts to acquire once the
+// maximum number of waiting Goroutines has been reached.
+func TestAcquireQueueFull(t *testing.T) {
+	ctx := context.Background()
+	sema := newSemaphore(1, WithMaxWaiters(1))
+
+	// Take the only spot available.
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+
+	// Fill the waiter queue.
+	done := make(chan error)
+	go func() {
+		done <- sema.Acquire(ctx)
+	}()
+	waitFor(sema, 1)
+
+	if err := sema.Acquire(ctx); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Acquire(%q) = %v; want %v", ctx, err, ErrQueueFull)
+	}
+
+	sema.Release(1000)
+	if err := <-done; err != nil {
+		t.Errorf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(1000)
+}
+
+// TestAcquireFIFO should grant spots to waiting Goroutines in the
+// same order they started waiting.
+func TestAcquireFIFO(t *testing.T) {
+	var mu sync.Mutex
+	var order []int // Order in which Goroutines acquired.
+	var wg sync.WaitGroup
+
+	n := 5 // Number of Goroutines to spin up.
+
+	ctx := context.Background()
+	sema := newSemaphore(1)
+
+	// Take the only spot available so everyone else queues.
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	for i := 0; i < n; i += 1 {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			if err := sema.Acquire(ctx); err != nil {
+				return
+			}
+
+			mu.Lock()
+			order = append(order, id)
+			mu.Unlock()
+
+			sema.Release(1000)
+		}(i)
+
+		// Ensure this Goroutine is queued before starting the next.
+		waitFor(sema, i+1)
+	}
+	sema.Release(1000)
+	wg.Wait()
+
+	for i, id := range order {
+		if id != i {
+			t.Errorf("order = %v; want ascending order", order)
+			break
+		}
+	}
+	if len(order) != n {
+		t.Errorf("len(order) = %d; want %d", len(order), n)
+	}
+}
+
+// TestAcquireWithPriority should grant spots to higher priority
+// Goroutines before lower priority Goroutines which waited longer.
+func TestAcquireWithPriority(t *testing.T) {
+	var mu sync.Mutex
+	var order []Priority // Order in which priorities acquired.
+	var wg sync.WaitGroup
+
+	prios := []Priority{PriorityLow, PriorityNormal, PriorityHigh}
+
+	ctx := context.Background()
+	sema := newSemaphore(1)
+
+	// Take the only spot available so everyone else queues.
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	for i, prio := range prios {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sema.AcquireWithPriority(ctx, prio); err != nil {
+				return
+			}
+
+			mu.Lock()
+			order = append(order, prio)
+			mu.Unlock()
+
+			sema.Release(1000)
+		}()
+		waitFor(sema, i+1)
+	}
+	sema.Release(1000)
+	wg.Wait()
+
+	exorder := []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+	for i := range exorder {
+		if i >= len(order) || order[i] != exorder[i] {
+			t.Errorf("order = %v; want %v", order, exorder)
+			break
+		}
+	}
+}
+
+// TestAcquireKeyed should grant spots to keys in turn, so a key with
+// many waiters does not starve a key with few.
+func TestAcquireKeyed(t *testing.T) {
+	var mu sync.Mutex
+	var order []string // Order in which keys acquired.
+	var wg sync.WaitGroup
+
+	keys := []string{"noisy", "noisy", "noisy", "quiet"}
+
+	ctx := context.Background()
+	sema := newSemaphore(1)
+
+	// Take the only spot available so everyone else queues.
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	for i, key := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sema.AcquireKeyed(ctx, key); err != nil {
+				return
+			}
+
+			mu.Lock()
+			order = append(order, key)
+			mu.Unlock()
+
+			sema.Release(1000)
+		}()
+		waitFor(sema, i+1)
+	}
+	sema.Release(1000)
+	wg.Wait()
+
+	exorder := []string{"noisy", "quiet", "noisy", "noisy"}
+	for i := range exorder {
+		if i >= len(order) || order[i] != exorder[i] {
+			t.Errorf("order = %v; want %v", order, exorder)
+			break
+		}
+	}
+}
+
+// TestAcquireInfo should report the pause cycles sat through and
+// the point balance when the spot was granted.
+func TestAcquireInfo(t *testing.T) {
+	ctx := context.Background()
+	sema := newSemaphore(1)
+
+	res, err := sema.AcquireInfo(ctx)
+	if err != nil {
+		t.Fatalf("AcquireInfo(%q) = %v; want nil", ctx, err)
+	}
+	if res.Pauses != 0 || res.Remaining != 1000 {
+		t.Errorf("AcquireInfo(%q) = %+v; want 0 pauses and 1000 remaining", ctx, res)
+	}
+
+	// Releasing at the threshold will pause for 1s, the next acquire
+	// should sit through it.
+	sema.Release(900)
+	res, err = sema.AcquireInfo(ctx)
+	if err != nil {
+		t.Fatalf("AcquireInfo(%q) = %v; want nil", ctx, err)
+	}
+	if res.Pauses != 1 {
+		t.Errorf("AcquireResult.Pauses = %d; want 1", res.Pauses)
+	}
+	if res.Remaining != 900 {
+		t.Errorf("AcquireResult.Remaining = %d; want 900", res.Remaining)
+	}
+	if res.Waited < 900*time.Millisecond {
+		t.Errorf("AcquireResult.Waited = %v; want at least 900ms", res.Waited)
+	}
+	sema.Release(1000)
+}
+
+// TestNewSemaphoreWithContext should cancel waiting Goroutines, and
+// any further attempts to acquire, once the context is cancelled.
+func TestNewSemaphoreWithContext(t *testing.T) {
+	ctx := context.Background()
+	sctx, cancel := context.WithCancel(ctx)
+	sema := NewSemaphoreWithContext(sctx, 1, NewBalance(900, 1000, 100))
+
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- sema.Acquire(ctx)
+	}()
+	waitFor(sema, 1)
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Acquire(%q) = %v; want %v", ctx, err, context.Canceled)
+	}
+	if err := sema.Acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Acquire(%q) = %v; want %v", ctx, err, context.Canceled)
+	}
+}
+
+// TestReleaseWithErr should release the spot without updating
+// the point balance and record the failure.
+func TestReleaseWithErr(t *testing.T) {
+	ctx := context.Background()
+	sema := newSemaphore(1)
+	exerr := errors.New("network error")
+
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.ReleaseWithErr(exerr)
+
+	st := sema.Stats()
+	if st.InFlight != 0 {
+		t.Errorf("Stats().InFlight = %d; want 0", st.InFlight)
+	}
+	if st.Remaining != 1000 {
+		t.Errorf("Stats().Remaining = %d; want 1000", st.Remaining)
+	}
+	if st.Failures != 1 || !errors.Is(st.LastErr, exerr) {
+		t.Errorf("Stats() = %+v; want 1 failure of %v", st, exerr)
+	}
+}
+
+// TestNewSemaphoreE should reject configuration which would
+// not work and accept configuration which would.
+func TestNewSemaphoreE(t *testing.T) {
+	tests := []struct {
+		cap   int
+		b     *Balance
+		exerr error
+	}{
+		{1, NewBalance(900, 1000, 100), nil},
+		{0, NewBalance(900, 1000, 100), nil},
+		{-1, NewBalance(900, 1000, 100), nil},
+		{1, nil, ErrNilBalance},
+		{1, NewBalance(900, 1000, 0), ErrInvalidRefillRate},
+	}
+	for _, tt := range tests {
+		sema, err := NewSemaphoreE(tt.cap, tt.b)
+		if !errors.Is(err, tt.exerr) {
+			t.Errorf("NewSemaphoreE(%d, %v) = %v; want %v", tt.cap, tt.b, err, tt.exerr)
+		}
+		if (err == nil) != (sema != nil) {
+			t.Errorf("NewSemaphoreE(%d, %v) = %v, %v; want one of semaphore or error", tt.cap, tt.b, sema, err)
+		}
+	}
+}
+
+// TestSetters should apply changed options to a Semaphore in use.
+func TestSetters(t *testing.T) {
+	paused := make(chan time.Duration, 1)
+	resumed := make(chan bool, 1)
+
+	ctx := context.Background()
+	sema := NewSemaphore(1, NewBalance(995, 1000, 100))
+	sema.SetPauseBuffer(10 * time.Millisecond)
+	sema.SetPauseFunc(func(_ int32, dur time.Duration) {
+		paused <- dur
+	})
+	sema.SetResumeFunc(func() {
+		resumed <- true
+	})
+
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(990) // At threshold, 10 points to refill at 100/s is 100ms.
+
+	exdur := 110 * time.Millisecond
+	if dur := <-paused; dur != exdur {
+		t.Errorf("PauseFunc(_, %v); want PauseFunc(_, %v)", dur, exdur)
+	}
+	<-resumed
+
+	sema.SetMaxWaiters(1)
+	if sema.MaxWaiters != 1 {
+		t.Errorf("MaxWaiters = %d; want 1", sema.MaxWaiters)
+	}
+	sema.SetAcquireBuffer(time.Millisecond)
+	if sema.AcquireBuffer != time.Millisecond {
+		t.Errorf("AcquireBuffer = %v; want %v", sema.AcquireBuffer, time.Millisecond)
+	}
+}
+
+// TestReleaseUnheld should panic when releasing a spot which was
+// never acquired, instead of allowing more than cap Goroutines to run.
+func TestReleaseUnheld(t *testing.T) {
+	sema := newSemaphore(1)
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Release(1000) did not panic; want panic")
+		}
+		if st := sema.Stats(); st.InFlight != 0 {
+			t.Errorf("Stats().InFlight = %d; want 0", st.InFlight)
+		}
+	}()
+	sema.Release(1000)
+}
+
+// TestNewSemaphoreWithContextDuringPause should clear the pause flag
+// when the context is cancelled part way through a pause.
+func TestNewSemaphoreWithContextDuringPause(t *testing.T) {
+	ctx := context.Background()
+	sctx, cancel := context.WithCancel(ctx)
+	sema := NewSemaphoreWithContext(sctx, 1, NewBalance(900, 1000, 100), WithResumeFunc(func() {
+		t.Errorf("ResumeFunc() called; want no resume after cancel")
+	}))
+
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(900) // Pause for 1s.
+	if st := sema.Stats(); !st.Paused {
+		t.Fatalf("Stats().Paused = %v; want true", st.Paused)
+	}
+
+	cancel()
+	for i := 0; sema.Stats().Paused; i += 1 {
+		if i > 100 {
+			t.Fatalf("Stats().Paused = true; want false")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestAcquireUnlimited should never make Goroutines wait on a spot
+// when there is no cap, only on a pause.
+func TestAcquireUnlimited(t *testing.T) {
+	n := 50 // Number of spots to acquire.
+
+	ctx := context.Background()
+	sema := newSemaphore(0)
+	for i := 0; i < n; i += 1 {
+		if err := sema.Acquire(ctx); err != nil {
+			t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+		}
+	}
+	if st := sema.Stats(); st.InFlight != n || st.Waiters != 0 {
+		t.Errorf("Stats() = %+v; want %d in flight and 0 waiters", st, n)
+	}
+
+	// Pausing should still apply, the 1s pause outlasts the deadline.
+	sema.Release(900)
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := sema.Acquire(ctx); !errors.Is(err, ErrDeadlineWouldExceed) {
+		t.Errorf("Acquire(%q) = %v; want %v", ctx, err, ErrDeadlineWouldExceed)
+	}
+}
+
+// TestBurst should allow more Goroutines than the cap to run while
+// points are plentiful, and shrink back once they decay.
+func TestBurst(t *testing.T) {
+	ctx := context.Background()
+	sema := NewSemaphore(2, NewBalance(100, 1000, 100), WithBurst(2, 0.8))
+
+	// Balance is full, should allow 4 spots.
+	for i := 0; i < 4; i += 1 {
+		if err := sema.Acquire(ctx); err != nil {
+			t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+		}
+	}
+
+	// Points decay below 80%, capacity shrinks back to 2 so releasing
+	// one spot should not allow another to be acquired.
+	sema.Release(500)
+	tctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := sema.Acquire(tctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire(%q) = %v; want %v", tctx, err, context.DeadlineExceeded)
+	}
+
+	sema.Release(500)
+	sema.Release(500)
+	if err := sema.Acquire(ctx); err != nil {
+		t.Errorf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+}
+
+// TestMinInterval should space out spots being granted.
+func TestMinInterval(t *testing.T) {
+	n := 4                         // Number of spots to acquire.
+	exdur := 20 * time.Millisecond // Expected spacing.
+
+	ctx := context.Background()
+	sema := newSemaphore(n, WithMinInterval(exdur))
+
+	start := time.Now()
+	for i := 0; i < n; i += 1 {
+		if err := sema.Acquire(ctx); err != nil {
+			t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+		}
+	}
+
+	// First is immediate, the rest are spaced out.
+	if dur := time.Since(start); dur < time.Duration(n-1)*exdur {
+		t.Errorf("duration = %v; want at least %v", dur, time.Duration(n-1)*exdur)
+	}
+}
+
+// TestHooks should call the OnAcquire and OnRelease hooks with
+// information about every acquire and release.
+func TestHooks(t *testing.T) {
+	var ares AcquireResult
+	var rinfo ReleaseInfo
+
+	ctx := context.Background()
+	sema := newSemaphore(1, WithOnAcquire(func(res AcquireResult) {
+		ares = res
+	}), WithOnRelease(func(info ReleaseInfo) {
+		rinfo = info
+	}))
+
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	if ares.Remaining != 1000 {
+		t.Errorf("OnAcquire(%+v); want 1000 remaining", ares)
+	}
+
+	sema.Release(950)
+	exinfo := ReleaseInfo{Before: 1000, After: 950, Delta: -50}
+	if rinfo != exinfo {
+		t.Errorf("OnRelease(%+v); want OnRelease(%+v)", rinfo, exinfo)
+	}
+}
+
+// TestAcquireDeadlineWouldExceed should reject straight away when the
+// pause would outlast the context's deadline.
+func TestAcquireDeadlineWouldExceed(t *testing.T) {
+	ctx := context.Background()
+	sema := newSemaphore(1)
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(900) // Pause for 1s.
+
+	tctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := sema.Acquire(tctx); !errors.Is(err, ErrDeadlineWouldExceed) {
+		t.Errorf("Acquire(%q) = %v; want %v", tctx, err, ErrDeadlineWouldExceed)
+	}
+	if dur := time.Since(start); dur > 100*time.Millisecond {
+		t.Errorf("Acquire(%q) took %v; want immediate", tctx, dur)
+	}
+}
+
+// TestAcquirePausedError should carry the remaining pause and points when
+// the context's deadline fires during a pause.
+func TestAcquirePausedError(t *testing.T) {
+	ctx := context.Background()
+	sema := newSemaphore(0)
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+
+	// Cancelled while waiting out the pause.
+	tctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sema.Release(900) // Pause for 1s.
+	go func() {
+		waitFor(sema, 1)
+		cancel()
+	}()
+	err := sema.Acquire(tctx)
+	var pe *PausedError
+	if !errors.As(err, &pe) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("Acquire(%q) = %v; want %v wrapped in PausedError", tctx, err, context.Canceled)
+	}
+	if pe.Remaining <= 0 || pe.Remaining > time.Second {
+		t.Errorf("PausedError.Remaining = %v; want between 0 and 1s", pe.Remaining)
+	}
+	if pe.Points != 900 || pe.Reason != PauseThresholdReached {
+		t.Errorf("PausedError = %+v; want Points 900, Reason %v", pe, PauseThresholdReached)
+	}
+}
+
+// TestAcquireCancelDuringPause should return promptly when the context
+// is cancelled while waiting out a pause.
+func TestAcquireCancelDuringPause(t *testing.T) {
+	ctx := context.Background()
+	sema := newSemaphore(1)
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(900) // Pause for 1s.
+
+	cctx, cancel := context.WithCancel(ctx)
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	if err := sema.Acquire(cctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Acquire(%q) = %v; want %v", cctx, err, context.Canceled)
+	}
+	if dur := time.Since(start); dur > 500*time.Millisecond {
+		t.Errorf("Acquire(%q) took %v; want prompt return", cctx, dur)
+	}
+	if st := sema.Stats(); st.Waiters != 0 {
+		t.Errorf("Stats().Waiters = %d; want 0", st.Waiters)
+	}
+}
+
+// TestDeprecatedAquire should ensure the previously misspelled API
+// still works as before.
+func TestDeprecatedAquire(t *testing.T) {
+	ctx := context.Background()
+	sema := newSemaphore(1, WithAquireBuffer(time.Millisecond))
+	if sema.AcquireBuffer != time.Millisecond || sema.AquireBuffer != time.Millisecond {
+		t.Errorf("AcquireBuffer, AquireBuffer = %v, %v; want %v", sema.AcquireBuffer, sema.AquireBuffer, time.Millisecond)
+	}
+	if err := sema.Aquire(ctx); err != nil {
+		t.Errorf("Aquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(1000)
+	if DefaultAquireBuffer != DefaultAcquireBuffer {
+		t.Errorf("DefaultAquireBuffer = %v; want %v", DefaultAquireBuffer, DefaultAcquireBuffer)
+	}
+}
+
+// TestCancelWaiters should wake every waiting Goroutine with the
+// supplied error, while the semaphore remains usable.
+func TestCancelWaiters(t *testing.T) {
+	n := 3 // Number of Goroutines to spin up.
+	exerr := errors.New("shop uninstalled")
+
+	ctx := context.Background()
+	sema := newSemaphore(1)
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+
+	errs := make(chan error, n)
+	for i := 0; i < n; i += 1 {
+		go func() {
+			errs <- sema.Acquire(ctx)
+		}()
+	}
+	waitFor(sema, n)
+
+	sema.CancelWaiters(exerr)
+	for i := 0; i < n; i += 1 {
+		if err := <-errs; !errors.Is(err, exerr) {
+			t.Errorf("Acquire(%q) = %v; want %v", ctx, err, exerr)
+		}
+	}
+
+	sema.Release(1000)
+	if err := sema.Acquire(ctx); err != nil {
+		t.Errorf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+}
+
+// TestWarmup should start with a single spot and ramp up to the
+// full capacity over the warmup duration.
+func TestWarmup(t *testing.T) {
+	ctx := context.Background()
+	sema := newSemaphore(3, WithWarmup(40*time.Millisecond))
+	if c := sema.Stats().Capacity; c != 1 {
+		t.Errorf("Stats().Capacity = %d; want 1", c)
+	}
+
+	// All three should be acquired once warmed up.
+	start := time.Now()
+	for i := 0; i < 3; i += 1 {
+		if err := sema.Acquire(ctx); err != nil {
+			t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+		}
+	}
+	if dur := time.Since(start); dur < 40*time.Millisecond {
+		t.Errorf("duration = %v; want at least 40ms", dur)
+	}
+	if c := sema.Stats().Capacity; c != 3 {
+		t.Errorf("Stats().Capacity = %d; want 3", c)
+	}
+}
+
+// TestCooldown should ramp the capacity back up after a pause.
+func TestCooldown(t *testing.T) {
+	ctx := context.Background()
+	sema := newSemaphore(3, WithCooldown(40*time.Millisecond))
+	if c := sema.Stats().Capacity; c != 3 {
+		t.Errorf("Stats().Capacity = %d; want 3", c)
+	}
+
+	// All three should be acquired once cooled down after the pause.
+	start := time.Now()
+	sema.PauseFor(5 * time.Millisecond)
+	for i := 0; i < 3; i += 1 {
+		if err := sema.Acquire(ctx); err != nil {
+			t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+		}
+	}
+	if dur := time.Since(start); dur < 45*time.Millisecond {
+		t.Errorf("duration = %v; want at least 45ms", dur)
+	}
+	if c := sema.Stats().Capacity; c != 3 {
+		t.Errorf("Stats().Capacity = %d; want 3", c)
+	}
+}
+
+// TestMaxWait should return a MaxWaitError, with the projected wait, when
+// a spot would not be granted within the MaxWait.
+func TestMaxWait(t *testing.T) {
+	ctx := context.Background()
+	sema := newSemaphore(1, WithMaxWait(20*time.Millisecond))
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+
+	// Spot is held, so waits the MaxWait before giving up.
+	start := time.Now()
+	err := sema.Acquire(ctx)
+	var mwe *MaxWaitError
+	if !errors.As(err, &mwe) || !errors.Is(err, ErrMaxWaitExceeded) {
+		t.Fatalf("Acquire(%q) = %v; want %v", ctx, err, ErrMaxWaitExceeded)
+	}
+	if dur := time.Since(start); dur < 20*time.Millisecond {
+		t.Errorf("duration = %v; want at least 20ms", dur)
+	}
+	if st := sema.Stats(); st.Waiters != 0 {
+		t.Errorf("Stats().Waiters = %d; want 0", st.Waiters)
+	}
+
+	// Pause of 1s is known to outlast the MaxWait, so rejected right away.
+	sema.Release(900)
+	start = time.Now()
+	err = sema.Acquire(ctx)
+	if !errors.As(err, &mwe) {
+		t.Fatalf("Acquire(%q) = %v; want %v", ctx, err, ErrMaxWaitExceeded)
+	}
+	if mwe.Wait < 500*time.Millisecond || mwe.MaxWait != 20*time.Millisecond {
+		t.Errorf("MaxWaitError = %+v; want projected wait near 1s and max 20ms", mwe)
+	}
+	if dur := time.Since(start); dur >= 20*time.Millisecond {
+		t.Errorf("duration = %v; want less than 20ms", dur)
+	}
+}
+
+// TestPauseJitter should extend pauses by up to the jitter fraction.
+func TestPauseJitter(t *testing.T) {
+	sema := newSemaphore(1, WithPauseJitter(0.5))
+	exdur := 100 * time.Millisecond
+	var varied bool
+	for i := 0; i < 20; i += 1 {
+		dur := sema.jitter(exdur)
+		if dur < exdur || dur > exdur+exdur/2 {
+			t.Errorf("jitter(%v) = %v; want between %v and %v", exdur, dur, exdur, exdur+exdur/2)
+		}
+		varied = varied || dur != exdur
+	}
+	if !varied {
+		t.Errorf("jitter(%v) never varied; want varied", exdur)
+	}
+
+	sema = newSemaphore(1)
+	if dur := sema.jitter(exdur); dur != exdur {
+		t.Errorf("jitter(%v) = %v; want %v", exdur, dur, exdur)
+	}
+}
+
+// TestMaxPause should clamp the pause duration.
+func TestMaxPause(t *testing.T) {
+	paused := make(chan time.Duration, 1)
+	ctx := context.Background()
+	sema := newSemaphore(1, WithMaxPause(20*time.Millisecond), WithPauseFunc(func(_ int32, dur time.Duration) {
+		paused <- dur
+	}))
+
+	// Would otherwise be a 10s pause.
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(0)
+	if dur := <-paused; dur != 20*time.Millisecond {
+		t.Errorf("PauseFunc(_, %v); want PauseFunc(_, 20ms)", dur)
+	}
+}
+
+// TestEarlyResume should resume from a pause once an update shows
+// the remaining points have recovered.
+func TestEarlyResume(t *testing.T) {
+	resumed := make(chan bool, 1)
+	ctx := context.Background()
+	sema := newSemaphore(2, WithEarlyResume(0.5), WithResumeFunc(func() {
+		resumed <- true
+	}))
+	for i := 0; i < 2; i += 1 {
+		if err := sema.Acquire(ctx); err != nil {
+			t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+		}
+	}
+
+	// 10s pause, then recovered by a later response.
+	sema.Release(0)
+	if st := sema.Stats(); !st.Paused {
+		t.Fatalf("Stats().Paused = false; want true")
+	}
+	sema.Release(800)
+	select {
+	case <-resumed:
+	case <-time.After(time.Second):
+		t.Fatalf("ResumeFunc not called; want called")
+	}
+	if st := sema.Stats(); st.Paused {
+		t.Errorf("Stats().Paused = true; want false")
+	}
+	if err := sema.Acquire(ctx); err != nil {
+		t.Errorf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+}
+
+// TestPauseTick should resume as soon as the threshold is cleared,
+// and continue past the duration while it has not.
+func TestPauseTick(t *testing.T) {
+	resumed := make(chan time.Time, 1)
+	ctx := context.Background()
+
+	// Refilling at 1000/s from 50, clears the threshold of 100 in around
+	// 50ms, well before the 950ms pause to refill back to full.
+	b := NewBalance(100, 1000, 1000)
+	sema := NewSemaphore(1, b, WithPauseTick(time.Millisecond), WithResumeFunc(func() {
+		resumed <- time.Now()
+	}))
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	start := time.Now()
+	sema.Release(50)
+	select {
+	case at := <-resumed:
+		if dur := at.Sub(start); dur > 500*time.Millisecond {
+			t.Errorf("resumed after %v; want well before 1s", dur)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("ResumeFunc not called; want called")
+	}
+
+	// Nothing refills, so the pause continues past its duration until
+	// the MaxPause.
+	m := &windowModel{pts: 10, max: 10, window: 5 * time.Millisecond}
+	sema = NewSemaphoreModel(1, m, WithPauseTick(time.Millisecond), WithMaxPause(50*time.Millisecond), WithResumeFunc(func() {
+		resumed <- time.Now()
+	}))
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	start = time.Now()
+	sema.Release(0)
+	at := <-resumed
+	if dur := at.Sub(start); dur < 50*time.Millisecond {
+		t.Errorf("resumed after %v; want at least 50ms", dur)
+	}
+}
@@ -0,0 +1,98 @@
+package shopifysemaphore
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ShopLimits bundles the Semaphores of the buckets a shop is limited by, the
+// GraphQL Admin API, the REST Admin API, and the Storefront API, behind a
+// single facade, as an app hitting every API of a shop would otherwise
+// compose them by hand. Each API is limited separately by Shopify, so each
+// has its own Semaphore.
+type ShopLimits struct {
+	Shop string // Shop the limits are for.
+
+	graphql    *Semaphore // Semaphore of the GraphQL Admin API.
+	rest       *Semaphore // Semaphore of the REST Admin API.
+	storefront *Semaphore // Semaphore of the Storefront API.
+}
+
+// NewShopLimits returns a pointer to ShopLimits for the shop, with a
+// Semaphore of capacity (cap) for each API, consuming the GraphQL Admin API
+// Balance (gql) and REST Admin API LeakyBucket (rest), or the presets of a
+// standard plan if nil. The options (opts) apply to every Semaphore, after
+// WithShop.
+func NewShopLimits(shop string, cap int, gql *Balance, rest *LeakyBucket, opts ...func(*Semaphore)) *ShopLimits {
+	if gql == nil {
+		gql = NewStandardGraphQLBalance()
+	}
+	if rest == nil {
+		rest = NewStandardRESTBucket()
+	}
+	opts = append([]func(*Semaphore){WithShop(shop)}, opts...)
+	return &ShopLimits{
+		Shop:       shop,
+		graphql:    NewSemaphore(cap, gql, opts...),
+		rest:       NewSemaphoreModel(cap, rest, opts...),
+		storefront: NewSemaphore(cap, NewStorefrontBalance(), opts...),
+	}
+}
+
+// ForGraphQL returns the Semaphore of the GraphQL Admin API.
+func (sl *ShopLimits) ForGraphQL() *Semaphore {
+	return sl.graphql
+}
+
+// ForREST returns the Semaphore of the REST Admin API.
+func (sl *ShopLimits) ForREST() *Semaphore {
+	return sl.rest
+}
+
+// ForStorefront returns the Semaphore of the Storefront API.
+func (sl *ShopLimits) ForStorefront() *Semaphore {
+	return sl.storefront
+}
+
+// For returns the Semaphore of the API the request (req) is to, or nil if
+// it is to none of them.
+func (sl *ShopLimits) For(req *http.Request) *Semaphore {
+	switch {
+	case IsStorefrontRequest(req):
+		return sl.storefront
+	case IsAdminRequest(req) && strings.HasSuffix(req.URL.Path, "/graphql.json"):
+		return sl.graphql
+	case IsAdminRequest(req):
+		return sl.rest
+	}
+	return nil
+}
+
+// Transport returns an http.RoundTripper which regulates each request made
+// by the base transport (base) through the Semaphore of the API it is to,
+// as chosen by For, passing requests to none of them straight through. A
+// nil base uses http.DefaultTransport.
+func (sl *ShopLimits) Transport(base http.RoundTripper) http.RoundTripper {
+	return shopTransport{sl: sl, base: base}
+}
+
+// shopTransport is the http.RoundTripper of ShopLimits.Transport.
+type shopTransport struct {
+	sl   *ShopLimits       // Limits of the shop.
+	base http.RoundTripper // Transport making the requests, http.DefaultTransport if nil.
+}
+
+// RoundTrip will make the request (req) through a Transport of the
+// Semaphore of the API the request is to.
+func (st shopTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sem := st.sl.For(req)
+	if sem == nil {
+		base := st.base
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		return base.RoundTrip(req)
+	}
+	tr := &Transport{Sem: sem, Base: st.base, Match: func(_ *http.Request) bool { return true }}
+	return tr.RoundTrip(req)
+}
@@ -0,0 +1,51 @@
+package shopifysemaphore
+
+import (
+	"net/http"
+	"testing"
+)
+
+// TestShopLimits should route each request to the Semaphore of its API.
+func TestShopLimits(t *testing.T) {
+	sl := NewShopLimits("example.myshopify.com", 2, nil, nil)
+	if sl.ForGraphQL().Shop != sl.Shop || sl.ForREST().Shop != sl.Shop || sl.ForStorefront().Shop != sl.Shop {
+		t.Errorf("Semaphore shops = %q, %q, %q; want %q", sl.ForGraphQL().Shop, sl.ForREST().Shop, sl.ForStorefront().Shop, sl.Shop)
+	}
+	if _, ok := sl.ForREST().model.(*LeakyBucket); !ok || sl.ForGraphQL().Balance == nil {
+		t.Errorf("ForREST().model = %T; want *LeakyBucket, and ForGraphQL() with a Balance", sl.ForREST().model)
+	}
+
+	tests := []struct {
+		url   string     // URL requested.
+		exsem *Semaphore // Expected Semaphore.
+	}{
+		{"https://example.myshopify.com/admin/api/2024-01/graphql.json", sl.ForGraphQL()},
+		{"https://example.myshopify.com/admin/api/2024-01/products.json", sl.ForREST()},
+		{"https://example.myshopify.com/api/2024-01/graphql.json", sl.ForStorefront()},
+		{"https://cdn.shopify.com/s/files/image.png", nil},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+		if sem := sl.For(req); sem != tt.exsem {
+			t.Errorf("For(%q) = %p; want %p", tt.url, sem, tt.exsem)
+		}
+	}
+}
+
+// TestShopLimitsTransport should regulate REST requests through the REST
+// Semaphore only.
+func TestShopLimitsTransport(t *testing.T) {
+	sl := NewShopLimits("example.myshopify.com", 2, nil, nil)
+	client := &http.Client{Transport: sl.Transport(respond(200, HeaderCallLimit, "39/40", "{}"))}
+	resp, err := client.Get("https://example.myshopify.com/admin/api/2024-01/products.json")
+	if err != nil {
+		t.Fatalf("Get() = %v; want nil", err)
+	}
+	resp.Body.Close()
+	if st := sl.ForREST().Stats(); st.Acquisitions != 1 || st.Remaining != 1 {
+		t.Errorf("ForREST().Stats() = %+v; want 1 acquisition, 1 remaining", st)
+	}
+	if st := sl.ForGraphQL().Stats(); st.Acquisitions != 0 {
+		t.Errorf("ForGraphQL().Stats().Acquisitions = %d; want 0", st.Acquisitions)
+	}
+}
@@ -0,0 +1,61 @@
+package shopifysemaphore
+
+import "time"
+
+// LastUpdatedAt returns when the remaining points were last updated, or
+// consumed from, or the zero time if they never have been updated.
+func (b *Balance) LastUpdatedAt() time.Time {
+	at := b.updatedAt.Load()
+	if at == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, at)
+}
+
+// Age returns how long it has been since the remaining points were
+// last updated, or 0 if they never have been.
+func (b *Balance) Age() time.Duration {
+	at := b.LastUpdatedAt()
+	if at.IsZero() {
+		return 0
+	}
+	return time.Since(at)
+}
+
+// stale returns true if the remaining points have not been updated within
+// StaleAfter, running the StaleFunc the first time it is noticed after
+// each update.
+func (b *Balance) stale() bool {
+	if b.StaleAfter <= 0 {
+		return false
+	}
+	age := b.Age()
+	if age < b.StaleAfter {
+		return false
+	}
+	if b.StaleFunc != nil && b.staleNotified.CompareAndSwap(false, true) {
+		go b.StaleFunc(age)
+	}
+	return true
+}
+
+// WithStaleAfter is a functional option for Balance which will consider the
+// remaining points stale once they have not been updated for the duration
+// (dur). While stale, Current assumes the balance to have refilled to full,
+// so a long idle period does not leave behind a low value which triggers a
+// pointless pause on the next request.
+func WithStaleAfter(dur time.Duration) func(*Balance) {
+	return func(b *Balance) {
+		b.StaleAfter = dur
+	}
+}
+
+// WithStaleFunc is a functional option for Balance to call when the remaining
+// points are first noticed to be stale, after StaleAfter, such as to trigger
+// a probe request for the actual balance. The age of the remaining points
+// will be passed into the function.
+func WithStaleFunc(fn func(time.Duration)) func(*Balance) {
+	return func(b *Balance) {
+		b.StaleFunc = fn
+	}
+}
@@ -0,0 +1,50 @@
+package shopifysemaphore
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAge should track how long since the remaining points were updated.
+func TestAge(t *testing.T) {
+	var b Balance
+	if age := b.Age(); age != 0 || !b.LastUpdatedAt().IsZero() {
+		t.Errorf("Balance.Age() = %v; want 0 when never updated", age)
+	}
+
+	b2 := newBalance()
+	time.Sleep(10 * time.Millisecond)
+	if age := b2.Age(); age < 10*time.Millisecond {
+		t.Errorf("Balance.Age() = %v; want at least 10ms", age)
+	}
+	b2.Update(500)
+	if age := b2.Age(); age >= 10*time.Millisecond {
+		t.Errorf("Balance.Age() = %v; want less than 10ms", age)
+	}
+}
+
+// TestStale should assume the balance is full once stale, running
+// the StaleFunc once per update.
+func TestStale(t *testing.T) {
+	ages := make(chan time.Duration, 2)
+	b := NewBalance(100, 1000, 0, WithStaleAfter(20*time.Millisecond), WithStaleFunc(func(age time.Duration) {
+		ages <- age
+	}))
+	b.Update(50)
+	if pts := b.Current(); pts != 50 {
+		t.Errorf("Balance.Current() = %d; want 50", pts)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if pts := b.Current(); pts != 1000 || b.AtThreshold() {
+		t.Errorf("Balance.Current() = %d; want 1000 and not at threshold", pts)
+	}
+	if age := <-ages; age < 20*time.Millisecond {
+		t.Errorf("StaleFunc(%v); want at least 20ms", age)
+	}
+	select {
+	case age := <-ages:
+		t.Errorf("StaleFunc(%v) called again; want once", age)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
@@ -0,0 +1,74 @@
+package shopifysemaphore
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// BalanceState represents a snapshot of the point information of a Balance,
+// which can be stored and later restored, such as by a crash restarted
+// worker, so it picks up where it left off instead of assuming a full bucket.
+type BalanceState struct {
+	Remaining    int32     `json:"remaining"`               // Point balance remaining.
+	Threshold    int32     `json:"threshold"`               // Minimum point balance before a pause.
+	ThresholdPct float64   `json:"threshold_pct,omitempty"` // Fraction of Limit the Threshold follows, 0 for absolute.
+	Limit        int32     `json:"limit"`                   // Maximum points available.
+	RefillRate   float64   `json:"refill_rate"`             // Number of points refilled per second.
+	UpdatedAt    time.Time `json:"updated_at"`              // When Remaining was last updated, zero if never.
+}
+
+// Snapshot returns the current point information of the Balance.
+func (b *Balance) Snapshot() BalanceState {
+	b.mu.RLock()
+	st := BalanceState{
+		Remaining:    b.Remaining.Load(),
+		Threshold:    b.Threshold,
+		ThresholdPct: b.ThresholdPct,
+		Limit:        b.Limit,
+		RefillRate:   b.RefillRate,
+	}
+	b.mu.RUnlock()
+	if at := b.updatedAt.Load(); at != 0 {
+		st.UpdatedAt = time.Unix(0, at)
+	}
+	return st
+}
+
+// Restore will replace the point information of the Balance with the
+// snapshot (st). The remaining points are restored as of when they were
+// last updated, so Current continues to account for points refilled since.
+func (b *Balance) Restore(st BalanceState) {
+	b.mu.Lock()
+	b.Threshold = st.Threshold
+	b.ThresholdPct = st.ThresholdPct
+	b.Limit = st.Limit
+	b.RefillRate = st.RefillRate
+	b.mu.Unlock()
+	var at int64
+	if !st.UpdatedAt.IsZero() {
+		at = st.UpdatedAt.UnixNano()
+	}
+	b.vmu.Lock()
+	old := b.Remaining.Swap(st.Remaining)
+	b.updatedAt.Store(at)
+	b.vmu.Unlock()
+	b.changed(old, st.Remaining, SourceRestore)
+}
+
+// MarshalJSON encodes the point information of the Balance, including when
+// the remaining points were last updated, so the state can be stored, such
+// as in Redis or a database, and restored on restart with UnmarshalJSON.
+func (b *Balance) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.Snapshot())
+}
+
+// UnmarshalJSON decodes the point information encoded by MarshalJSON into
+// the Balance, in the same way as Restore.
+func (b *Balance) UnmarshalJSON(data []byte) error {
+	var st BalanceState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return err
+	}
+	b.Restore(st)
+	return nil
+}
@@ -0,0 +1,44 @@
+package shopifysemaphore
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestBalanceJSON should encode and decode the point information,
+// including when the remaining points were last updated.
+func TestBalanceJSON(t *testing.T) {
+	b := NewBalancePct(0.1, 1000, 0.5)
+	b.Update(400)
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatalf("json.Marshal(b) = %v; want nil", err)
+	}
+
+	var rb Balance
+	if err := json.Unmarshal(data, &rb); err != nil {
+		t.Fatalf("json.Unmarshal(%s) = %v; want nil", data, err)
+	}
+	if st, rst := b.Snapshot(), rb.Snapshot(); st != rst {
+		t.Errorf("json.Unmarshal(%s) = %+v; want %+v", data, rst, st)
+	}
+
+	if err := json.Unmarshal([]byte(`{"remaining":"x"}`), &rb); err == nil {
+		t.Errorf("json.Unmarshal(invalid) = nil; want error")
+	}
+}
+
+// TestSnapshot should restore the point information of a Balance
+// from a snapshot.
+func TestSnapshot(t *testing.T) {
+	b := newBalance()
+	b.Update(250)
+	st := b.Snapshot()
+
+	rb := newBalance()
+	rb.Restore(st)
+	if rst := rb.Snapshot(); rst != st {
+		t.Errorf("Restore(%+v) = %+v; want %+v", st, rst, st)
+	}
+}
@@ -0,0 +1,113 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrStateNotFound is returned by a Store when no state is saved for a key.
+var ErrStateNotFound = errors.New("shopifysemaphore: balance state not found")
+
+// Store represents somewhere a BalanceState can be saved and loaded from,
+// keyed, such as by shop domain. It allows for the state to outlive the
+// process, such as in Redis or a database.
+type Store interface {
+	Save(ctx context.Context, key string, st BalanceState) error // Save the state for the key.
+	Load(ctx context.Context, key string) (BalanceState, error)  // Load the state for the key, or ErrStateNotFound.
+}
+
+// MemoryStore is a Store which holds states in memory. It does not outlive
+// the process, but is useful for tests or sharing state between Semaphores.
+type MemoryStore struct {
+	mu     sync.Mutex              // For handling the states.
+	states map[string]BalanceState // Saved states, by key.
+}
+
+// NewMemoryStore returns a pointer to an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: make(map[string]BalanceState)}
+}
+
+// Save will save the state (st) for the key.
+func (ms *MemoryStore) Save(_ context.Context, key string, st BalanceState) error {
+	defer ms.mu.Unlock()
+	ms.mu.Lock()
+	ms.states[key] = st
+	return nil
+}
+
+// Load will return the state for the key, or ErrStateNotFound.
+func (ms *MemoryStore) Load(_ context.Context, key string) (BalanceState, error) {
+	defer ms.mu.Unlock()
+	ms.mu.Lock()
+	st, ok := ms.states[key]
+	if !ok {
+		return BalanceState{}, ErrStateNotFound
+	}
+	return st, nil
+}
+
+// FileStore is a Store which saves each state as a JSON file in a
+// directory, named by its key.
+type FileStore struct {
+	Dir string // Directory the state files are saved in.
+}
+
+// NewFileStore returns a pointer to a FileStore saving to the directory (dir),
+// which must already exist.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{Dir: dir}
+}
+
+// Save will save the state (st) for the key. The file is written in full
+// before replacing any previous state, so a crash mid-save does not leave
+// a partially written state behind.
+func (fst *FileStore) Save(_ context.Context, key string, st BalanceState) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(fst.Dir, ".state-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), fst.path(key))
+}
+
+// Load will return the state for the key, or ErrStateNotFound.
+func (fst *FileStore) Load(_ context.Context, key string) (BalanceState, error) {
+	data, err := os.ReadFile(fst.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return BalanceState{}, ErrStateNotFound
+	}
+	if err != nil {
+		return BalanceState{}, err
+	}
+
+	var st BalanceState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return BalanceState{}, err
+	}
+	return st, nil
+}
+
+// path returns the path of the state file for the key, escaped so
+// the key can not point outside of the directory.
+func (fst *FileStore) path(key string) string {
+	return filepath.Join(fst.Dir, url.PathEscape(key)+".json")
+}
@@ -0,0 +1,45 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// testStore should save and load states for a Store.
+func testStore(t *testing.T, s Store) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := s.Load(ctx, "shop.myshopify.com"); !errors.Is(err, ErrStateNotFound) {
+		t.Errorf("Load(%q, shop.myshopify.com) = %v; want %v", ctx, err, ErrStateNotFound)
+	}
+
+	b := newBalance()
+	b.Update(400)
+	if err := s.Save(ctx, "shop.myshopify.com", b.Snapshot()); err != nil {
+		t.Fatalf("Save(%q, shop.myshopify.com, _) = %v; want nil", ctx, err)
+	}
+	st, err := s.Load(ctx, "shop.myshopify.com")
+	if err != nil {
+		t.Fatalf("Load(%q, shop.myshopify.com) = _, %v; want nil", ctx, err)
+	}
+
+	rb := NewBalance(0, 0, 1)
+	rb.Restore(st)
+	if rb.Remaining.Load() != 400 || rb.Limit != 1000 {
+		t.Errorf("Restore(%+v) = %d, %d; want 400, 1000", st, rb.Remaining.Load(), rb.Limit)
+	}
+	if !st.UpdatedAt.Equal(b.Snapshot().UpdatedAt) {
+		t.Errorf("UpdatedAt = %v; want %v", st.UpdatedAt, b.Snapshot().UpdatedAt)
+	}
+}
+
+// TestMemoryStore should save and load states in memory.
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+// TestFileStore should save and load states as files.
+func TestFileStore(t *testing.T) {
+	testStore(t, NewFileStore(t.TempDir()))
+}
@@ -0,0 +1,39 @@
+package shopifysemaphore
+
+import (
+	"net/http"
+	"strings"
+)
+
+// StatusSecurityRejection is the status code of a Storefront API response
+// when the request was rejected as abusive, such as when the requests from a
+// single IP are too frequent. The Storefront API does not limit by a point
+// balance, so this, or a 429 Too Many Requests, is the only signal of being
+// throttled.
+const StatusSecurityRejection = 430
+
+// StorefrontQueryLimit is the maximum cost of a single Storefront API query.
+const StorefrontQueryLimit int32 = 1000
+
+// IsStorefrontRequest returns true if the request (req) is to the Shopify
+// Storefront API, such as https://example.myshopify.com/api/2024-01/graphql.json.
+func IsStorefrontRequest(req *http.Request) bool {
+	return strings.HasPrefix(req.URL.Path, "/api/") && strings.HasSuffix(req.URL.Path, "/graphql.json")
+}
+
+// NewStorefrontTransport returns a pointer to Transport, regulating requests
+// to the Storefront API made by the base transport (base) through the
+// Semaphore (sem). As the Storefront API reports no point balance, the
+// Semaphore only pauses once throttled, for the Retry-After of the response.
+// A nil base uses http.DefaultTransport.
+func NewStorefrontTransport(sem *Semaphore, base http.RoundTripper) *Transport {
+	return &Transport{Sem: sem, Base: base, Match: IsStorefrontRequest}
+}
+
+// NewStorefrontBalance returns a pointer to Balance for Storefront API
+// requests, with the limit of StorefrontQueryLimit, so the same Semaphore
+// options apply to Storefront requests. As no point balance is reported, it
+// stays at the limit and never reaches its threshold.
+func NewStorefrontBalance(opts ...func(*Balance)) *Balance {
+	return NewBalance(0, StorefrontQueryLimit, StorefrontQueryLimit, opts...)
+}
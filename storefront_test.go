@@ -0,0 +1,45 @@
+package shopifysemaphore
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestIsStorefrontRequest should match requests to the Storefront API only.
+func TestIsStorefrontRequest(t *testing.T) {
+	tests := []struct {
+		url  string // URL requested.
+		exok bool   // Expected to match.
+	}{
+		{"https://example.myshopify.com/api/2024-01/graphql.json", true},
+		{"https://example.myshopify.com/admin/api/2024-01/graphql.json", false},
+		{"https://example.myshopify.com/api/unstable/graphql", false},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodPost, tt.url, nil)
+		if ok := IsStorefrontRequest(req); ok != tt.exok {
+			t.Errorf("IsStorefrontRequest(%q) = %v; want %v", tt.url, ok, tt.exok)
+		}
+	}
+}
+
+// TestStorefrontTransport should pause once a Storefront request is rejected.
+func TestStorefrontTransport(t *testing.T) {
+	paused := make(chan time.Duration, 1)
+	sema := NewSemaphore(1, NewStorefrontBalance(), WithPauseFunc(func(_ int32, dur time.Duration) {
+		paused <- dur
+	}))
+	client := &http.Client{Transport: NewStorefrontTransport(sema, respond(StatusSecurityRejection, "", "", "Security Rejection"))}
+	resp, err := client.Post("https://example.myshopify.com/api/2024-01/graphql.json", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Post() = %v; want nil", err)
+	}
+	resp.Body.Close()
+	if dur := <-paused; dur != DefaultPauseBuffer {
+		t.Errorf("PauseFunc(_, %v); want PauseFunc(_, %v)", dur, DefaultPauseBuffer)
+	}
+	if st := sema.Stats(); st.InFlight != 0 || st.LastErr != ErrThrottled || st.Remaining != StorefrontQueryLimit {
+		t.Errorf("Stats() = %+v; want released with %v", st, ErrThrottled)
+	}
+}
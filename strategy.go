@@ -0,0 +1,128 @@
+package shopifysemaphore
+
+import "time"
+
+// PauseStrategy represents the policy of when to pause and for how long,
+// evaluated by a Semaphore each time a spot is released and the point
+// balance has been updated. It is called while the Semaphore's lock is
+// held, so it is never called concurrently by the same Semaphore, and
+// must not call back into it.
+type PauseStrategy interface {
+	// Pause returns how long to pause for, given the point balance model (m)
+	// and the PauseBuffer (buf), and false if no pause should happen.
+	Pause(m BalanceModel, buf time.Duration) (time.Duration, bool)
+}
+
+// RefillStrategy is the default PauseStrategy. It pauses once the point
+// balance is at or below its threshold, for as long as it takes to refill
+// back to full, plus the PauseBuffer.
+type RefillStrategy struct{}
+
+// Pause returns the duration to refill back to full, plus the buffer (buf),
+// if the point balance model (m) is at or below its threshold.
+func (RefillStrategy) Pause(m BalanceModel, buf time.Duration) (time.Duration, bool) {
+	if !m.AtThreshold() {
+		return 0, false
+	}
+	return m.RefillDuration() + buf, true
+}
+
+// WithPauseStrategy is a functional option for Semaphore which will set
+// the policy (ps) of when to pause and for how long, replacing the default
+// of RefillStrategy.
+func WithPauseStrategy(ps PauseStrategy) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.PauseStrategy = ps
+	}
+}
+
+// BackoffStrategy is a PauseStrategy which, once the point balance is at
+// or below its threshold, pauses for at least the Base duration, doubling
+// for each consecutive pause up to the Max. A pause is consecutive when it
+// happens within the Window of the previous pause ending, such as when the
+// refill based estimate keeps proving too optimistic and the balance is
+// exhausted again right after resuming. The refill based duration is still
+// used if it is longer. It must not be shared between Semaphores.
+type BackoffStrategy struct {
+	Base   time.Duration // Duration of the first pause.
+	Max    time.Duration // Maximum duration of a pause, 0 for no maximum.
+	Window time.Duration // Duration after a pause ends where another pause is consecutive.
+
+	n    int       // Number of consecutive pauses before the current one.
+	ends time.Time // When the last pause returned ends.
+}
+
+// NewBackoffStrategy returns a pointer to BackoffStrategy, accepting the
+// duration of the first pause (base), the maximum duration of a pause
+// (max), and the window after a pause ends where another is consecutive.
+func NewBackoffStrategy(base time.Duration, max time.Duration, window time.Duration) *BackoffStrategy {
+	return &BackoffStrategy{Base: base, Max: max, Window: window}
+}
+
+// Pause returns the backed off duration to pause for if the point balance
+// model (m) is at or below its threshold, or the duration to refill back to
+// full plus the buffer (buf) if it is longer.
+func (bs *BackoffStrategy) Pause(m BalanceModel, buf time.Duration) (time.Duration, bool) {
+	now := time.Now()
+	if !m.AtThreshold() || now.Before(bs.ends) {
+		// Not at the threshold, or still within the last pause.
+		return 0, false
+	}
+
+	if !bs.ends.IsZero() && now.Sub(bs.ends) <= bs.Window {
+		bs.n += 1
+	} else {
+		bs.n = 0
+	}
+	dur := bs.Base
+	for i := 0; i < bs.n && (bs.Max <= 0 || dur < bs.Max); i += 1 {
+		dur *= 2
+	}
+	dur = max(dur, m.RefillDuration()+buf)
+	if bs.Max > 0 {
+		dur = min(dur, bs.Max)
+	}
+	bs.ends = now.Add(dur)
+	return dur, true
+}
+
+// predicateModel is a BalanceModel which replaces the AtThreshold check of
+// the model with the PausePredicate, passed the Balance.
+type predicateModel struct {
+	BalanceModel
+
+	b  *Balance            // Balance passed to the predicate, nil for other models.
+	fn func(*Balance) bool // Predicate of when to pause.
+}
+
+// AtThreshold returns the result of the PausePredicate.
+func (pm predicateModel) AtThreshold() bool {
+	return pm.fn(pm.b)
+}
+
+// pauseModel returns the point balance model to pass to the PauseStrategy,
+// with the AtThreshold check replaced by the PausePredicate, if set, and
+// raised by the ThresholdPct of an active Window. It must be called while
+// holding mu.
+func (sem *Semaphore) pauseModel() BalanceModel {
+	m := sem.model
+	if sem.PausePredicate != nil {
+		m = predicateModel{BalanceModel: m, b: sem.Balance, fn: sem.PausePredicate}
+	}
+	if w, _, ok := sem.window(time.Now()); ok && w.ThresholdPct > 0 {
+		m = scheduleModel{BalanceModel: m, pct: w.ThresholdPct}
+	}
+	return m
+}
+
+// WithPausePredicate is a functional option for Semaphore which will replace
+// the AtThreshold check of when to pause, as used by the PauseStrategy, with
+// the predicate (fn). This allows pausing on compound conditions, such as the
+// remaining points being below the threshold while the consumption rate is
+// exceeding the refill rate. For a BalanceModel other than Balance, the
+// predicate is passed nil.
+func WithPausePredicate(fn func(*Balance) bool) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.PausePredicate = fn
+	}
+}
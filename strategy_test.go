@@ -0,0 +1,101 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fixedStrategy is a PauseStrategy which always pauses for a
+// fixed duration.
+type fixedStrategy time.Duration
+
+func (fs fixedStrategy) Pause(_ BalanceModel, _ time.Duration) (time.Duration, bool) {
+	return time.Duration(fs), true
+}
+
+// TestRefillStrategy should pause to refill back to full once at
+// the threshold.
+func TestRefillStrategy(t *testing.T) {
+	b := newBalance()
+	if _, ok := (RefillStrategy{}).Pause(b, time.Second); ok {
+		t.Errorf("RefillStrategy.Pause() = _, true; want false above threshold")
+	}
+	b.Update(0)
+	if dur, ok := (RefillStrategy{}).Pause(b, time.Second); !ok || dur != 11*time.Second {
+		// Should be 11s as (1000-0)/100 = 10, plus the 1s buffer.
+		t.Errorf("RefillStrategy.Pause() = %v, %v; want 11s, true", dur, ok)
+	}
+}
+
+// TestWithPauseStrategy should pause based on the strategy.
+func TestWithPauseStrategy(t *testing.T) {
+	paused := make(chan time.Duration, 1)
+	ctx := context.Background()
+	sema := newSemaphore(1, WithPauseStrategy(fixedStrategy(10*time.Millisecond)), WithPauseFunc(func(_ int32, dur time.Duration) {
+		paused <- dur
+	}))
+
+	// Well above the threshold, but the strategy always pauses.
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(1000)
+	if dur := <-paused; dur != 10*time.Millisecond {
+		t.Errorf("PauseFunc(_, %v); want PauseFunc(_, 10ms)", dur)
+	}
+}
+
+// TestBackoffStrategy should double the pause for consecutive pauses,
+// up to the maximum, and reset once outside of the window.
+func TestBackoffStrategy(t *testing.T) {
+	b := &windowModel{pts: 10, max: 10} // Refills instantly.
+	bs := NewBackoffStrategy(time.Millisecond, 4*time.Millisecond, 20*time.Millisecond)
+	if _, ok := bs.Pause(b, 0); ok {
+		t.Errorf("BackoffStrategy.Pause() = _, true; want false above threshold")
+	}
+
+	b.Update(0)
+	for _, exdur := range []time.Duration{1, 2, 4, 4} {
+		dur, ok := bs.Pause(b, 0)
+		if !ok || dur != exdur*time.Millisecond {
+			t.Errorf("BackoffStrategy.Pause() = %v, %v; want %v, true", dur, ok, exdur*time.Millisecond)
+		}
+		if _, ok := bs.Pause(b, 0); ok {
+			t.Errorf("BackoffStrategy.Pause() = _, true; want false within the pause")
+		}
+		time.Sleep(dur)
+	}
+
+	// Outside of the window, back to the base.
+	time.Sleep(30 * time.Millisecond)
+	if dur, _ := bs.Pause(b, 0); dur != time.Millisecond {
+		t.Errorf("BackoffStrategy.Pause() = %v; want 1ms", dur)
+	}
+}
+
+// TestWithPausePredicate should pause based on the predicate instead
+// of the threshold.
+func TestWithPausePredicate(t *testing.T) {
+	ctx := context.Background()
+	sema := newSemaphore(1, WithMaxPause(5*time.Millisecond), WithPausePredicate(func(b *Balance) bool {
+		return b.Current() < 500
+	}))
+
+	// Below the threshold of 900, but not the predicate.
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(800)
+	if st := sema.Stats(); st.Paused {
+		t.Errorf("Stats().Paused = true; want false")
+	}
+
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(400)
+	if st := sema.Stats(); !st.Paused {
+		t.Errorf("Stats().Paused = false; want true")
+	}
+}
@@ -0,0 +1,55 @@
+package shopifysemaphore
+
+import "time"
+
+// SoftThrottle represents the spacing between spots being granted, set by
+// WithSoftThrottle, which increases linearly as the remaining points decay
+// from Above towards the threshold. Throughput then degrades smoothly, making
+// a hard pause a last resort.
+type SoftThrottle struct {
+	Above float64       // Fraction of Limit below which spacing begins.
+	Max   time.Duration // Spacing once the remaining points reach the threshold.
+}
+
+// interval returns the spacing for the remaining points, as the fraction (f)
+// of the maximum, given the threshold as a fraction (th) of the maximum.
+func (st SoftThrottle) interval(f float64, th float64) time.Duration {
+	if st.Max <= 0 || f >= st.Above {
+		return 0
+	}
+	if f <= th || st.Above <= th {
+		return st.Max
+	}
+	return time.Duration(float64(st.Max) * (st.Above - f) / (st.Above - th))
+}
+
+// interval returns the spacing between spots being granted, the larger of the
+// MinInterval and the SoftThrottle for the remaining points.
+// It must be called while holding mu.
+func (sem *Semaphore) interval() time.Duration {
+	if sem.softThrottle == nil {
+		return sem.MinInterval
+	}
+	lim := sem.model.Max()
+	if lim <= 0 {
+		return sem.MinInterval
+	}
+	var th float64
+	if sem.Balance != nil {
+		// Only a Balance has a threshold to approach.
+		th = float64(sem.Balance.threshold()+sem.Balance.Floor) / float64(lim)
+	}
+	f := float64(sem.model.Current()) / float64(lim)
+	return max(sem.MinInterval, sem.softThrottle.interval(f, th))
+}
+
+// WithSoftThrottle is a functional option for Semaphore which will space out
+// spots being granted once the remaining points fall below the fraction
+// (above) of the Limit, increasing linearly up to the duration (max) as they
+// reach the threshold. For a BalanceModel other than Balance, the spacing
+// reaches the maximum as the remaining points reach 0.
+func WithSoftThrottle(above float64, max time.Duration) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.softThrottle = &SoftThrottle{Above: above, Max: max}
+	}
+}
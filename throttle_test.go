@@ -0,0 +1,58 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestSoftThrottleInterval should increase the spacing linearly from
+// Above down to the threshold.
+func TestSoftThrottleInterval(t *testing.T) {
+	st := SoftThrottle{Above: 0.5, Max: 40 * time.Millisecond}
+	tests := []struct {
+		f    float64       // Fraction of the maximum remaining.
+		exiv time.Duration // Expected interval.
+	}{
+		{1, 0},
+		{0.5, 0},
+		{0.3, 20 * time.Millisecond},
+		{0.1, 40 * time.Millisecond},
+		{0, 40 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if iv := st.interval(tt.f, 0.1); iv != tt.exiv {
+			t.Errorf("SoftThrottle.interval(%v, 0.1) = %v; want %v", tt.f, iv, tt.exiv)
+		}
+	}
+}
+
+// TestWithSoftThrottle should space out spots once the remaining points
+// fall below the fraction.
+func TestWithSoftThrottle(t *testing.T) {
+	ctx := context.Background()
+	sema := NewSemaphore(0, NewBalance(100, 1000, 1), WithSoftThrottle(0.5, 40*time.Millisecond))
+
+	// Plenty of points, no spacing.
+	start := time.Now()
+	for i := 0; i < 2; i += 1 {
+		if err := sema.Acquire(ctx); err != nil {
+			t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+		}
+	}
+	if dur := time.Since(start); dur > 10*time.Millisecond {
+		t.Errorf("duration = %v; want immediate", dur)
+	}
+
+	// Halfway between Above and the threshold, 20ms spacing.
+	sema.Release(300)
+	start = time.Now()
+	for i := 0; i < 2; i += 1 {
+		if err := sema.Acquire(ctx); err != nil {
+			t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+		}
+	}
+	if dur := time.Since(start); dur < 20*time.Millisecond {
+		t.Errorf("duration = %v; want at least 20ms", dur)
+	}
+}
@@ -0,0 +1,112 @@
+package shopifysemaphore
+
+import "slices"
+
+// TierAction represents the behavior of the Semaphore while the remaining
+// points are at or below a Tier.
+type TierAction int
+
+const (
+	TierWarn  TierAction = iota // Only run the Tier's callback.
+	TierHalve                   // Halve the capacity while at or below the Tier.
+	TierPause                   // Pause, in the same way as reaching the Threshold.
+)
+
+// Tier represents one of several thresholds, set by WithTiers, allowing for
+// graceful degradation as the remaining points decay, such as warning at 50%,
+// halving concurrency at 20%, and pausing at 5%.
+type Tier struct {
+	Pct    float64           // Fraction of Limit at or below which the Tier applies.
+	Action TierAction        // Behavior while the Tier applies.
+	Func   func(Tier, int32) // Optional callback for when the Tier is entered, with the remaining points.
+}
+
+// tierDepth returns the number of tiers, from the start of sem.tiers which is
+// sorted highest first, applying to the current remaining points.
+// It must be called while holding mu.
+func (sem *Semaphore) tierDepth() int {
+	lim := sem.model.Max()
+	if lim <= 0 {
+		return 0
+	}
+	f := float64(sem.model.Current()) / float64(lim)
+	n := 0
+	for n < len(sem.tiers) && f <= sem.tiers[n].Pct {
+		n += 1
+	}
+	return n
+}
+
+// evalTiers will evaluate the tiers against the current remaining points,
+// running the callback of each newly entered Tier and applying their
+// actions. It returns true if a Tier calls for a pause.
+// It must be called while holding mu.
+func (sem *Semaphore) evalTiers(pts int32) bool {
+	n := sem.tierDepth()
+	for _, tier := range sem.tiers[min(sem.tierAt, n):n] {
+		if tier.Func != nil {
+			go sem.hook("Tier.Func", func() { tier.Func(tier, pts) })
+		}
+	}
+	sem.tierAt = n
+
+	var pause bool
+	sem.halved = false
+	for _, tier := range sem.tiers[:n] {
+		switch tier.Action {
+		case TierHalve:
+			sem.halved = true
+		case TierPause:
+			pause = true
+		}
+	}
+	return pause
+}
+
+// WithTiers is a functional option for Semaphore which will set several
+// thresholds, each with its own action and callback, in addition to the
+// Threshold of the Balance. A Tier's callback is run each time the remaining
+// points decay to it, after having been above it.
+func WithTiers(tiers ...Tier) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.tiers = slices.Clone(tiers)
+		slices.SortFunc(sem.tiers, func(a, b Tier) int {
+			// Highest first, so deeper tiers follow.
+			switch {
+			case a.Pct > b.Pct:
+				return -1
+			case a.Pct < b.Pct:
+				return 1
+			}
+			return 0
+		})
+	}
+}
+
+// evalWarn will run the WarnFunc if the remaining points have first dipped
+// below the WarnThreshold, since being at or above it.
+// It must be called while holding mu.
+func (sem *Semaphore) evalWarn() {
+	if sem.warnFunc == nil {
+		return
+	}
+	pts := sem.model.Current()
+	below := pts < sem.warnAt
+	if below && !sem.warned {
+		fn := sem.warnFunc
+		go sem.hook("WarnFunc", func() { fn(pts) })
+	}
+	sem.warned = below
+}
+
+// WithWarnThreshold is a functional option for Semaphore which will run the
+// function (fn) with the remaining points once they first dip below pts,
+// a soft level well above the Threshold, so optional work can be shed before
+// any pause happens. It is run again only after the remaining points have
+// recovered to pts or above.
+func WithWarnThreshold(pts int32, fn func(int32)) func(*Semaphore) {
+	return func(sem *Semaphore) {
+		sem.warnAt = pts
+		sem.warnFunc = fn
+	}
+}
@@ -0,0 +1,103 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestTiers should apply the action of each Tier, and run its callback,
+// as the remaining points decay through them.
+func TestTiers(t *testing.T) {
+	entered := make(chan float64, 3)
+	fn := func(tier Tier, _ int32) {
+		entered <- tier.Pct
+	}
+
+	ctx := context.Background()
+	sema := NewSemaphore(4, NewBalance(0, 1000, 100), WithTiers(
+		Tier{Pct: 0.05, Action: TierPause, Func: fn},
+		Tier{Pct: 0.5, Action: TierWarn, Func: fn},
+		Tier{Pct: 0.2, Action: TierHalve, Func: fn},
+	))
+
+	recv := func(expct float64) {
+		t.Helper()
+		select {
+		case pct := <-entered:
+			if pct != expct {
+				t.Errorf("Tier.Func(%v); want Tier.Func(%v)", pct, expct)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("Tier.Func not called; want Tier.Func(%v)", expct)
+		}
+	}
+
+	// Warn only, capacity is unchanged.
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(500)
+	recv(0.5)
+	if st := sema.Stats(); st.Capacity != 4 || st.Paused {
+		t.Errorf("Stats() = %+v; want capacity 4 and not paused", st)
+	}
+
+	// Halved.
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(200)
+	recv(0.2)
+	if st := sema.Stats(); st.Capacity != 2 || st.Paused {
+		t.Errorf("Stats() = %+v; want capacity 2 and not paused", st)
+	}
+
+	// Paused, even though the Threshold is 0.
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	sema.Release(50)
+	recv(0.05)
+	if st := sema.Stats(); !st.Paused {
+		t.Errorf("Stats() = %+v; want paused", st)
+	}
+}
+
+// TestWarnThreshold should run the callback once when the remaining points
+// first dip below the soft level, and again only after recovering.
+func TestWarnThreshold(t *testing.T) {
+	warned := make(chan int32, 4)
+	ctx := context.Background()
+	sema := NewSemaphore(1, NewBalance(0, 1000, 1), WithWarnThreshold(500, func(pts int32) {
+		warned <- pts
+	}))
+	for _, pts := range []int32{400, 300, 800, 450} {
+		if err := sema.Acquire(ctx); err != nil {
+			t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+		}
+		sema.Release(pts)
+	}
+
+	// Callbacks run in their own Goroutines, so may arrive in any order.
+	var sum int32
+	for i := 0; i < 2; i += 1 {
+		select {
+		case pts := <-warned:
+			if pts != 400 && pts != 450 {
+				t.Errorf("WarnFunc(%d); want WarnFunc(400) or WarnFunc(450)", pts)
+			}
+			sum += pts
+		case <-time.After(time.Second):
+			t.Fatalf("WarnFunc not called; want called twice")
+		}
+	}
+	if sum != 850 {
+		t.Errorf("WarnFunc called with a sum of %d; want 850", sum)
+	}
+	select {
+	case pts := <-warned:
+		t.Errorf("WarnFunc(%d) called; want called twice", pts)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
@@ -0,0 +1,182 @@
+package shopifysemaphore
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transport is an http.RoundTripper which regulates requests to the Shopify
+// Admin API through a Semaphore. A spot is acquired before each request, and
+// released once the response is received through ReleaseFromResponse,
+// removing the Acquire and Release plumbing from clients. Requests not to the
+// Admin API, as decided by Match, are passed straight through.
+type Transport struct {
+	Sem   *Semaphore               // Semaphore regulating the requests.
+	Base  http.RoundTripper        // Transport making the requests, http.DefaultTransport if nil.
+	Match func(*http.Request) bool // Decides which requests are regulated, IsAdminRequest if nil.
+}
+
+// NewTransport returns a pointer to Transport, regulating requests made by
+// the base transport (base) through the Semaphore (sem). A nil base uses
+// http.DefaultTransport.
+func NewTransport(sem *Semaphore, base http.RoundTripper) *Transport {
+	return &Transport{Sem: sem, Base: base}
+}
+
+// IsAdminRequest returns true if the request (req) is to the Shopify Admin
+// API, such as https://example.myshopify.com/admin/api/2024-01/graphql.json.
+func IsAdminRequest(req *http.Request) bool {
+	return strings.HasPrefix(req.URL.Path, "/admin/")
+}
+
+// RoundTrip will acquire a spot for the request (req), in the request's
+// context, and make the request through the base transport, releasing the
+// spot with ReleaseFromResponse. A request which fails, such as from a
+// network error, is released with the error.
+func (tr *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := tr.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	match := tr.Match
+	if match == nil {
+		match = IsAdminRequest
+	}
+	if !match(req) {
+		return base.RoundTrip(req)
+	}
+
+	if err := tr.Sem.Acquire(req.Context()); err != nil {
+		return nil, err
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		tr.Sem.ReleaseWithErr(err)
+		return nil, err
+	}
+	if resp.Request == nil {
+		resp.Request = req
+	}
+	if err := tr.Sem.ReleaseFromResponse(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ReleaseFromResponse will release a spot with the remaining points reported
+// by the response (resp) to a Shopify API request, covering the usual
+// integration in one call. The remaining points are read from the cost
+// extension of a GraphQL response, or the HeaderCallLimit header of a REST
+// response, and the body of a GraphQL response is replaced so it can still be
+// read by the caller. A response of 429 Too Many Requests is released with
+// ErrThrottled, pausing through PauseFromRetryAfter, as is a response of
+// StatusSecurityRejection, pausing for at least DefaultPauseBuffer without a
+// Retry-After header. A GraphQL response throttled with a 200 OK is also
+// released with ErrThrottled, pausing through HandleGraphQLErrors. The
+// Balance, if any, follows the limit and restore rate of a GraphQL response's
+// throttleStatus through SyncCost. If the body can not be read, the spot is
+// released with the error, which is returned.
+func (sem *Semaphore) ReleaseFromResponse(resp *http.Response) error {
+	pts, gql, err := remainingFrom(resp)
+	if err != nil {
+		// Body could not be read, it is of no use to the caller either.
+		sem.ReleaseWithErr(err)
+		return err
+	}
+	if gql != nil && sem.Balance != nil {
+		// Follow the bucket size and restore rate of the shop's plan.
+		sem.Balance.SyncCost(*gql.Extensions.Cost)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		sem.PauseFromRetryAfter(resp.Header)
+		sem.release(pts, ErrThrottled, false)
+		return nil
+	case StatusSecurityRejection:
+		// Rejected without point information, wait out at least the buffer.
+		if !sem.PauseFromRetryAfter(resp.Header) {
+			sem.mu.Lock()
+			sem.hold(sem.remaining(), DefaultPauseBuffer, PauseThrottled)
+			sem.mu.Unlock()
+		}
+		sem.release(pts, ErrThrottled, false)
+		return nil
+	}
+	if gql != nil && sem.HandleGraphQLErrors(*gql) {
+		sem.release(pts, ErrThrottled, false)
+		return nil
+	}
+	sem.Release(pts)
+	return nil
+}
+
+// remainingFrom returns the remaining points reported by the response (resp),
+// or ErrPts if none are reported, along with the parsed GraphQL response, nil
+// if not a GraphQL response. A GraphQL response's body is read to find the
+// cost extension, and replaced so it can still be read by the caller. Without
+// the request of the response, the body is assumed to be of GraphQL.
+func remainingFrom(resp *http.Response) (int32, *GraphQLResponse, error) {
+	if used, lim, err := ParseCallLimit(resp.Header.Get(HeaderCallLimit)); err == nil {
+		return max(lim-used, 0), nil, nil
+	}
+	if req := resp.Request; req != nil && !strings.HasSuffix(req.URL.Path, "/graphql.json") {
+		return ErrPts, nil, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return ErrPts, nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	gql, err := ParseGraphQLResponse(body)
+	if err != nil || gql.Extensions.Cost == nil {
+		// Not a cost bearing response, such as an error page.
+		return ErrPts, nil, nil
+	}
+	return gql.Extensions.Cost.Remaining(), &gql, nil
+}
+
+// PauseFromRetryAfter will pause the Semaphore for exactly the duration of the
+// Retry-After header (h) of a 429 Too Many Requests response, with the
+// PauseThrottled reason, overriding the refill estimate. The pause is held
+// for the duration even if the point balance recovers, and a pause already
+// in progress is extended to at least the duration. It will return false if
+// the header is missing or can not be parsed, in which case this is a no-op.
+func (sem *Semaphore) PauseFromRetryAfter(h http.Header) bool {
+	dur, ok := parseRetryAfter(h.Get("Retry-After"), time.Now())
+	if !ok {
+		return false
+	}
+
+	defer sem.mu.Unlock()
+	sem.mu.Lock()
+	sem.hold(sem.remaining(), dur, PauseThrottled)
+	return true
+}
+
+// parseRetryAfter returns the duration from now of the value (v) of the
+// Retry-After header, which is either in seconds, possibly fractional, such
+// as "2.0", or a HTTP date. It returns false if it can not be parsed.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs * float64(time.Second)), true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	return max(t.Sub(now), 0), true
+}
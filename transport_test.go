@@ -0,0 +1,189 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// roundTripperFunc is an http.RoundTripper from a function.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+// respond returns an http.RoundTripper which responds with the status,
+// header (k, v), and body.
+func respond(status int, k string, v string, body string) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{
+			StatusCode: status,
+			Header:     make(http.Header),
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}
+		if k != "" {
+			resp.Header.Set(k, v)
+		}
+		return resp, nil
+	})
+}
+
+// TestTransport should release with the remaining points reported by
+// GraphQL and REST responses.
+func TestTransport(t *testing.T) {
+	body := `{"data":{},"extensions":{"cost":{"throttleStatus":{"maximumAvailable":1000.0,"currentlyAvailable":850.0}}}}`
+	tests := []struct {
+		url   string            // URL requested.
+		rt    http.RoundTripper // Base transport.
+		expts int32             // Expected remaining points.
+	}{
+		{"https://example.myshopify.com/admin/api/2024-01/graphql.json", respond(200, "", "", body), 850},
+		{"https://example.myshopify.com/admin/api/2024-01/products.json", respond(200, HeaderCallLimit, "32/40", "{}"), 8},
+		{"https://example.myshopify.com/admin/api/2024-01/graphql.json", respond(502, "", "", "Bad Gateway"), 1000},
+	}
+	for _, tt := range tests {
+		sema := newSemaphore(1)
+		client := &http.Client{Transport: NewTransport(sema, tt.rt)}
+		resp, err := client.Get(tt.url)
+		if err != nil {
+			t.Fatalf("Get(%q) = %v; want nil", tt.url, err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if len(b) == 0 {
+			t.Errorf("Get(%q) body empty; want body", tt.url)
+		}
+		if st := sema.Stats(); st.InFlight != 0 || st.Remaining != tt.expts || st.Acquisitions != 1 {
+			t.Errorf("Get(%q) Stats() = %+v; want released with %d remaining", tt.url, st, tt.expts)
+		}
+	}
+}
+
+// TestTransportErrors should release with the error for failed and
+// throttled requests, and pass through requests not to the Admin API.
+func TestTransportErrors(t *testing.T) {
+	neterr := errors.New("connection reset")
+	sema := newSemaphore(1)
+	client := &http.Client{Transport: NewTransport(sema, roundTripperFunc(func(_ *http.Request) (*http.Response, error) {
+		return nil, neterr
+	}))}
+	if _, err := client.Get("https://example.myshopify.com/admin/api/2024-01/shop.json"); !errors.Is(err, neterr) {
+		t.Errorf("Get() = %v; want %v", err, neterr)
+	}
+	if st := sema.Stats(); st.InFlight != 0 || !errors.Is(st.LastErr, neterr) {
+		t.Errorf("Stats() = %+v; want released with %v", st, neterr)
+	}
+
+	sema = newSemaphore(1)
+	client = &http.Client{Transport: NewTransport(sema, respond(429, "", "", "Too Many Requests"))}
+	resp, err := client.Get("https://example.myshopify.com/admin/api/2024-01/shop.json")
+	if err != nil {
+		t.Fatalf("Get() = %v; want nil", err)
+	}
+	resp.Body.Close()
+	if st := sema.Stats(); st.InFlight != 0 || !errors.Is(st.LastErr, ErrThrottled) {
+		t.Errorf("Stats() = %+v; want released with %v", st, ErrThrottled)
+	}
+
+	// Not to the Admin API, not regulated.
+	resp, err = client.Get("https://cdn.shopify.com/s/files/image.png")
+	if err != nil {
+		t.Fatalf("Get() = %v; want nil", err)
+	}
+	resp.Body.Close()
+	if st := sema.Stats(); st.Acquisitions != 1 {
+		t.Errorf("Stats().Acquisitions = %d; want 1", st.Acquisitions)
+	}
+}
+
+// TestPauseFromRetryAfter should pause for exactly the Retry-After duration.
+func TestPauseFromRetryAfter(t *testing.T) {
+	sema := newSemaphore(1)
+	if sema.PauseFromRetryAfter(http.Header{}) {
+		t.Error("PauseFromRetryAfter() = true; want false")
+	}
+
+	paused := make(chan PauseInfo, 1)
+	sema.SetPauseInfoFunc(func(info PauseInfo) { paused <- info })
+	if !sema.PauseFromRetryAfter(http.Header{"Retry-After": {"2.0"}}) {
+		t.Fatal("PauseFromRetryAfter() = false; want true")
+	}
+	if info := <-paused; info.Duration != 2*time.Second || info.Reason != PauseThrottled {
+		t.Errorf("PauseInfoFunc(%+v); want 2s and %v", info, PauseThrottled)
+	}
+
+	// Already paused, the pause is extended to the new duration.
+	sema.PauseFromRetryAfter(http.Header{"Retry-After": {"4"}})
+	sema.mu.Lock()
+	if ends := sema.pauseEnds.Sub(sema.pausedAt); ends < 4*time.Second {
+		t.Errorf("pause ends after %v; want 4s", ends)
+	}
+	sema.mu.Unlock()
+}
+
+// TestTransportRetryAfter should pause for the Retry-After of a 429 response
+// rather than the refill estimate.
+func TestTransportRetryAfter(t *testing.T) {
+	paused := make(chan time.Duration, 1)
+	sema := newSemaphore(1, WithPauseFunc(func(_ int32, dur time.Duration) { paused <- dur }))
+	client := &http.Client{Transport: NewTransport(sema, respond(429, "Retry-After", "3", "Too Many Requests"))}
+	resp, err := client.Get("https://example.myshopify.com/admin/api/2024-01/shop.json")
+	if err != nil {
+		t.Fatalf("Get() = %v; want nil", err)
+	}
+	resp.Body.Close()
+	if dur := <-paused; dur != 3*time.Second {
+		t.Errorf("PauseFunc(_, %v); want PauseFunc(_, 3s)", dur)
+	}
+}
+
+// TestParseRetryAfter should parse seconds and HTTP dates.
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		v     string        // Header value.
+		exdur time.Duration // Expected duration.
+		exok  bool          // Expected to parse.
+	}{
+		{"2", 2 * time.Second, true},
+		{"2.5", 2500 * time.Millisecond, true},
+		{now.Add(5 * time.Second).Format(http.TimeFormat), 5 * time.Second, true},
+		{now.Add(-5 * time.Second).Format(http.TimeFormat), 0, true},
+		{"", 0, false},
+		{"-1", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		if dur, ok := parseRetryAfter(tt.v, now); dur != tt.exdur || ok != tt.exok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.v, dur, ok, tt.exdur, tt.exok)
+		}
+	}
+}
+
+// TestReleaseFromResponse should release with the remaining points of a
+// response made outside of a Transport.
+func TestReleaseFromResponse(t *testing.T) {
+	ctx := context.Background()
+	sema := newSemaphore(1)
+	if err := sema.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire(%q) = %v; want nil", ctx, err)
+	}
+	// Without the request, the body is assumed to be of GraphQL.
+	body := `{"extensions":{"cost":{"throttleStatus":{"maximumAvailable":1000.0,"currentlyAvailable":920.0,"restoreRate":50.0}}}}`
+	resp := &http.Response{StatusCode: 200, Header: make(http.Header), Body: io.NopCloser(strings.NewReader(body))}
+	if err := sema.ReleaseFromResponse(resp); err != nil {
+		t.Fatalf("ReleaseFromResponse() = %v; want nil", err)
+	}
+	if st := sema.Stats(); st.InFlight != 0 || st.Remaining != 920 {
+		t.Errorf("Stats() = %+v; want released with 920 remaining", st)
+	}
+	if b, _ := io.ReadAll(resp.Body); string(b) != body {
+		t.Errorf("Body = %q; want %q", b, body)
+	}
+}
@@ -0,0 +1,96 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// watchBuffer is the number of changes buffered for each watcher
+// before the oldest are dropped.
+const watchBuffer = 16
+
+// Watch returns a channel which receives the remaining points each time they
+// change, through Update, Sync, Consume, Reserve, Refund, or Restore, so dashboards and
+// schedulers can react to consumption without polling. A slow receiver may
+// miss older changes, but will always receive the latest. The channel is
+// closed once ctx is done.
+func (b *Balance) Watch(ctx context.Context) <-chan int32 {
+	ch := make(chan int32, watchBuffer)
+	b.wmu.Lock()
+	if b.watchers == nil {
+		b.watchers = make(map[chan int32]struct{})
+	}
+	b.watchers[ch] = struct{}{}
+	b.wmu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		defer b.wmu.Unlock()
+		b.wmu.Lock()
+		delete(b.watchers, ch)
+		close(ch)
+	}()
+	return ch
+}
+
+// changed will record the change of remaining points, from old to pts,
+// into the history and send it to every watcher, dropping a watcher's
+// oldest change if its buffer is full.
+func (b *Balance) changed(old int32, pts int32, src UpdateSource) {
+	defer b.wmu.Unlock()
+	b.wmu.Lock()
+	b.record(UpdateRecord{At: time.Now(), Old: old, New: pts, Source: src})
+	for ch := range b.watchers {
+		select {
+		case ch <- pts:
+			continue
+		default:
+		}
+
+		// Buffer is full, make room for the latest.
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- pts:
+		default:
+		}
+	}
+}
+
+// WaitUntilAbove will block until the current remaining points, accounting
+// for those refilled since the last update, are above pts, such as to gate
+// an expensive bulk mutation until the bucket has genuinely recovered. It
+// wakes as points are projected to refill and whenever they change, and
+// returns ctx.Err() if ctx is done first.
+func (b *Balance) WaitUntilAbove(ctx context.Context, pts int32) error {
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	changes := b.Watch(wctx)
+
+	t := time.NewTimer(time.Hour)
+	defer t.Stop()
+	for {
+		cur := b.Current()
+		if cur > pts {
+			return nil
+		}
+
+		// Wake once projected to have refilled above, or on the next
+		// change if nothing refills.
+		var refilled <-chan time.Time
+		if rr := b.refillRate(); rr > 0 {
+			secs := float64(pts+1-cur) / rr
+			t.Reset(time.Duration(math.Ceil(secs * float64(time.Second))))
+			refilled = t.C
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changes:
+		case <-refilled:
+		}
+	}
+}
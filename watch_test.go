@@ -0,0 +1,94 @@
+package shopifysemaphore
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestWatch should receive each change to the remaining points,
+// closing once the context is done.
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := newBalance()
+	ch := b.Watch(ctx)
+
+	b.Update(800)
+	b.Consume(100)
+	b.Refund(50)
+	b.Update(ErrPts) // Not a change.
+	for _, expts := range []int32{800, 700, 750} {
+		select {
+		case pts := <-ch:
+			if pts != expts {
+				t.Errorf("Watch() = %d; want %d", pts, expts)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Watch() did not receive; want %d", expts)
+		}
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Errorf("Watch() received; want closed")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Watch() not closed; want closed")
+	}
+}
+
+// TestWatchSlow should drop the oldest changes for a slow receiver,
+// keeping the latest.
+func TestWatchSlow(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := newBalance()
+	ch := b.Watch(ctx)
+
+	for i := 0; i < watchBuffer*2; i += 1 {
+		b.Update(int32(i))
+	}
+	var last int32
+	for len(ch) > 0 {
+		last = <-ch
+	}
+	if exlast := int32(watchBuffer*2 - 1); last != exlast {
+		t.Errorf("Watch() latest = %d; want %d", last, exlast)
+	}
+}
+
+// TestWaitUntilAbove should wait until the remaining points are above
+// the level, through refilling or an update.
+func TestWaitUntilAbove(t *testing.T) {
+	ctx := context.Background()
+
+	// Refilling at 1000/s from 500, takes around 50ms to be above 550.
+	b := NewBalance(0, 1000, 1000)
+	b.Update(500)
+	start := time.Now()
+	if err := b.WaitUntilAbove(ctx, 550); err != nil {
+		t.Errorf("Balance.WaitUntilAbove(%q, 550) = %v; want nil", ctx, err)
+	}
+	if dur := time.Since(start); dur < 40*time.Millisecond {
+		t.Errorf("duration = %v; want around 50ms", dur)
+	}
+
+	// Nothing refills, woken by an update.
+	b = NewBalance(0, 1000, 0)
+	b.Update(0)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		b.Update(600)
+	}()
+	if err := b.WaitUntilAbove(ctx, 550); err != nil {
+		t.Errorf("Balance.WaitUntilAbove(%q, 550) = %v; want nil", ctx, err)
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := b.WaitUntilAbove(tctx, 1000); err != context.DeadlineExceeded {
+		t.Errorf("Balance.WaitUntilAbove(%q, 1000) = %v; want %v", tctx, err, context.DeadlineExceeded)
+	}
+}